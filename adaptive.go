@@ -0,0 +1,97 @@
+package main
+
+import (
+	"context"
+	"sync"
+)
+
+// Rate limit headroom, as a fraction of the limit, above which every worker
+// runs and below which only one does. In between the number of workers
+// scales linearly.
+const (
+	adaptiveHigh = 0.5
+	adaptiveLow  = 0.1
+)
+
+// concurrency limits how many words are searched at once with
+// -adaptive-threads, between one and -threads, following the rate limit
+// budget GitHub reports after each request.
+type concurrency struct {
+	mu     sync.Mutex
+	cond   *sync.Cond
+	max    int
+	limit  int
+	active int
+}
+
+// searchLimit is set with -adaptive-threads, and nil otherwise.
+var searchLimit *concurrency
+
+func newConcurrency(max int) *concurrency {
+	c := &concurrency{max: max, limit: max}
+	c.cond = sync.NewCond(&c.mu)
+	return c
+}
+
+// acquire waits until fewer words are being searched than the current limit,
+// or ctx is done, and reports whether the caller may search.
+func (c *concurrency) acquire(ctx context.Context) bool {
+	if c == nil {
+		return true
+	}
+
+	stop := context.AfterFunc(ctx, func() {
+		c.mu.Lock()
+		c.cond.Broadcast()
+		c.mu.Unlock()
+	})
+	defer stop()
+
+	c.mu.Lock()
+	defer c.mu.Unlock()
+	for c.active >= c.limit && ctx.Err() == nil {
+		c.cond.Wait()
+	}
+	if ctx.Err() != nil {
+		return false
+	}
+	c.active++
+	return true
+}
+
+func (c *concurrency) release() {
+	if c == nil {
+		return
+	}
+
+	c.mu.Lock()
+	c.active--
+	c.cond.Signal()
+	c.mu.Unlock()
+}
+
+// observe sets the limit from the remaining rate limit budget. It is the
+// Searcher's RateFunc.
+func (c *concurrency) observe(platform string, remaining, limit int) {
+	if limit <= 0 {
+		return
+	}
+
+	headroom := float64(remaining) / float64(limit)
+	workers := 1
+	switch {
+	case headroom >= adaptiveHigh:
+		workers = c.max
+	case headroom > adaptiveLow:
+		workers = max(1, int(float64(c.max)*(headroom-adaptiveLow)/(adaptiveHigh-adaptiveLow)))
+	}
+
+	c.mu.Lock()
+	defer c.mu.Unlock()
+	if workers == c.limit {
+		return
+	}
+	verbosePrint("Searching %d words at once, with %d/%d %s requests remaining\n", workers, remaining, limit, platformNames[platform])
+	c.limit = workers
+	c.cond.Broadcast()
+}
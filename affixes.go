@@ -0,0 +1,62 @@
+package main
+
+import (
+	"bufio"
+	"fmt"
+	"os"
+	"strings"
+)
+
+const (
+	prefixMarker = "prefix:"
+	suffixMarker = "suffix:"
+)
+
+// loadAffixes reads an affix file where each line is "prefix:<token>" or
+// "suffix:<token>". Blank lines and lines starting with # are ignored.
+func loadAffixes(name string) (prefixes, suffixes []string, err error) {
+	file, err := os.Open(name)
+	if err != nil {
+		return nil, nil, err
+	}
+	defer file.Close()
+
+	scanner := bufio.NewScanner(file)
+	for lineNum := 1; scanner.Scan(); lineNum++ {
+		line := strings.TrimSpace(scanner.Text())
+		switch {
+		case line == "" || strings.HasPrefix(line, "#"):
+		case strings.HasPrefix(line, prefixMarker):
+			prefixes = append(prefixes, strings.TrimPrefix(line, prefixMarker))
+		case strings.HasPrefix(line, suffixMarker):
+			suffixes = append(suffixes, strings.TrimPrefix(line, suffixMarker))
+		default:
+			return nil, nil, fmt.Errorf("%s:%d: expected %q or %q, got %q", name, lineNum, prefixMarker, suffixMarker, line)
+		}
+	}
+
+	return prefixes, suffixes, scanner.Err()
+}
+
+// applyAffixes adds prefix+word and word+suffix for every word in words that
+// contains no whitespace, returning how many new words were added.
+func applyAffixes(words *wordList, prefixes, suffixes []string) int {
+	var bases []string
+	for _, word := range words.words {
+		if word != "" && !spaceRegexp.MatchString(word) {
+			bases = append(bases, word)
+		}
+	}
+
+	before := words.len()
+	for _, word := range bases {
+		for _, prefix := range prefixes {
+			addSources(addWord(words, prefix+word), wordSources[word]...)
+		}
+		for _, suffix := range suffixes {
+			addSources(addWord(words, word+suffix), wordSources[word]...)
+		}
+	}
+
+	return words.len() - before
+}
@@ -0,0 +1,92 @@
+package main
+
+import (
+	"crypto/sha256"
+	"encoding/hex"
+	"encoding/json"
+	"os"
+	"path/filepath"
+	"strconv"
+	"time"
+
+	"github.com/codingo/dorky/internal/atomicfile"
+	"github.com/codingo/dorky/pkg/dorky"
+)
+
+// cacheEntry is the on-disk form of one search's results.
+type cacheEntry struct {
+	Created time.Time      `json:"created"`
+	Results []dorky.Result `json:"results"`
+}
+
+// cacheKey names the cache file for a search. The mode, result limit,
+// instance, -star-min, and -org or -user scope are part of the key because
+// they change what the API returns.
+func cacheKey(mode, platform, instance, category, query string, maxResults int) string {
+	key := mode + "\x00" + platform + "\x00" + category + "\x00" + query + "\x00" + strconv.Itoa(maxResults)
+	if flags.orgScope != "" || flags.userScope != "" {
+		key += "\x00" + flags.orgScope + "\x00" + flags.userScope
+	}
+	if instance != "" {
+		key += "\x00" + instance
+	}
+	if flags.starMinFlag > 0 && platform == platformGitHub && category == categoryRepo {
+		key += "\x00stars:" + strconv.Itoa(flags.starMinFlag)
+	}
+	sum := sha256.Sum256([]byte(key))
+	return hex.EncodeToString(sum[:]) + ".json"
+}
+
+// loadCache returns the cached results for key if they are younger than ttl.
+func loadCache(dir, key string, ttl time.Duration) ([]dorky.Result, bool) {
+	data, err := os.ReadFile(filepath.Join(dir, key))
+	if err != nil {
+		return nil, false
+	}
+
+	var entry cacheEntry
+	if err := json.Unmarshal(data, &entry); err != nil {
+		return nil, false
+	}
+	if time.Since(entry.Created) > ttl {
+		return nil, false
+	}
+	return entry.Results, true
+}
+
+// storeCache saves results under key.
+func storeCache(dir, key string, results []dorky.Result) error {
+	data, err := json.Marshal(cacheEntry{Created: time.Now(), Results: results})
+	if err != nil {
+		return err
+	}
+	return atomicfile.Write(dir, key, data)
+}
+
+// cachedSearch returns the results of fetch, served from the -cache directory
+// when a fresh entry exists. With -no-cache the cache is not read, but fresh
+// results still replace what it holds.
+func cachedSearch(mode, platform, instance, category, query string, maxResults int, fetch func() ([]dorky.Result, error)) ([]dorky.Result, error) {
+	if flags.cacheFlag == "" {
+		return fetch()
+	}
+
+	key := cacheKey(mode, platform, instance, category, query, maxResults)
+	if !flags.noCacheFlag {
+		if results, ok := loadCache(flags.cacheFlag, key, flags.cacheTTLFlag); ok {
+			verbosePrint("Cache hit for %s matching '%s'\n", categoryLabels[platform][category], query)
+			return results, nil
+		}
+		verbosePrint("Cache miss for %s matching '%s'\n", categoryLabels[platform][category], query)
+	}
+
+	results, err := fetch()
+	if err != nil {
+		return nil, err
+	}
+
+	if err := storeCache(flags.cacheFlag, key, results); err != nil {
+		errorPrint("Error writing cache: %s\n", err)
+	}
+	return results, nil
+}
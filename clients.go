@@ -0,0 +1,367 @@
+package main
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"net/url"
+	"os"
+	"strings"
+	"time"
+	"unicode"
+
+	"github.com/codingo/dorky/pkg/dorky"
+	"github.com/google/go-github/v38/github"
+	"github.com/xanzy/go-gitlab"
+)
+
+// gitlabInstances holds a Searcher with only a GitLab client for each
+// instance when -gl-url or GITLAB_URL names more than one. The main Searcher
+// then has no GitLab client.
+var gitlabInstances []*dorky.Searcher
+
+// createSearcher creates a client for each enabled platform. Credentials for
+// platforms selected with -platforms or the per-platform flags are required,
+// so a failure there is fatal; otherwise platforms without credentials are
+// skipped.
+func createSearcher(cfg config) *dorky.Searcher {
+	s := newSearcher(cfg)
+	explicit := len(selectedPlatforms(cfg)) > 0
+
+	categories := categorySet(cfg)
+	s.CombineGitHubAccounts = categories[categoryOrg] && categories[categoryUser] && !cfg.checkFlag
+
+	fail := func(name string, err error, required bool) {
+		if explicit || required {
+			fmt.Fprintf(os.Stderr, "Error creating %s client: %s\n", name, err)
+			os.Exit(1)
+		}
+		verbosePrint("Skipping %s: %s\n", name, err)
+	}
+
+	var err error
+	if platformEnabled(cfg, platformGitHub) {
+		if s.GitHub, err = createGitHubClient(cfg); err != nil {
+			fail("GitHub", err, cfg.ghTokenFile != "" || cfg.ghTokensFlag != "")
+		}
+	}
+
+	if platformEnabled(cfg, platformGitLab) {
+		baseURLs := gitlabURLs(cfg)
+		if len(baseURLs) <= 1 {
+			baseURL := getenv("GITLAB_URL")
+			if len(baseURLs) == 1 {
+				baseURL = baseURLs[0]
+			}
+			if s.GitLab, err = createGitLabClient(cfg, baseURL, true); err != nil {
+				fail("GitLab", err, cfg.glTokenFile != "")
+			}
+		} else {
+			for i, baseURL := range baseURLs {
+				instance := newSearcher(cfg)
+				instance.Instance = gitlabHost(baseURL)
+				if instance.GitLab, err = createGitLabClient(cfg, baseURL, i == 0); err != nil {
+					fail("GitLab at "+instance.Instance, err, i == 0 && cfg.glTokenFile != "")
+					continue
+				}
+				gitlabInstances = append(gitlabInstances, instance)
+			}
+		}
+	}
+
+	if platformEnabled(cfg, platformBitbucket) {
+		if s.Bitbucket, err = createBitbucketClient(cfg); err != nil {
+			fail("Bitbucket", err, false)
+		}
+	}
+
+	if platformEnabled(cfg, platformGitea) {
+		if s.Gitea, err = createGiteaClient(cfg); err != nil {
+			fail("Gitea", err, false)
+		}
+	}
+
+	// Azure DevOps needs an organization to search, so it is only tried
+	// when -az-org is given.
+	if platformEnabled(cfg, platformAzure) && cfg.azOrgFlag != "" {
+		if s.Azure, err = createAzureClient(cfg); err != nil {
+			fail("Azure DevOps", err, false)
+		}
+	}
+
+	if platformEnabled(cfg, platformSourceHut) {
+		if s.SourceHut, err = createSourceHutClient(cfg); err != nil {
+			fail("SourceHut", err, false)
+		} else if categories[categoryOrg] {
+			errorPrint("SourceHut has no organizations, so -o is skipped there\n")
+		}
+	}
+
+	if cfg.starMinFlag > 0 && categories[categoryRepo] {
+		for _, platform := range searchedPlatforms(s) {
+			if !starPlatforms[platform] && dorky.Supported(platform, categoryRepo) {
+				errorPrint("%s does not report repository stars, so -star-min leaves its repositories unfiltered\n", platformNames[platform])
+			}
+		}
+	}
+
+	if cfg.langFlag != "" && categories[categoryRepo] {
+		for _, platform := range searchedPlatforms(s) {
+			if !languagePlatforms[platform] {
+				errorPrint("%s does not report repository languages, so -lang leaves its repositories unfiltered\n", platformNames[platform])
+			}
+		}
+	}
+
+	if scope := scopeFlagName(cfg); scope != "" {
+		for _, platform := range searchedPlatforms(s) {
+			if platform != platformGitHub && platform != platformGitLab && dorky.Supported(platform, categoryRepo) {
+				errorPrint("%s does not support %s, so its repositories are searched everywhere\n", platformNames[platform], scope)
+			}
+		}
+	}
+
+	if cfg.resolveFlag && !cfg.checkFlag {
+		for _, platform := range searchedPlatforms(s) {
+			if !resolvePlatforms[platform] && dorky.Supported(platform, categoryUser) {
+				errorPrint("%s does not support -resolve, so its users are printed as found\n", platformNames[platform])
+			}
+		}
+	}
+
+	if cfg.expandOrgsFlag {
+		for _, platform := range searchedPlatforms(s) {
+			if !expandPlatforms[platform] && dorky.Supported(platform, categoryOrg) {
+				errorPrint("%s does not support -expand-orgs, so its organizations are not expanded\n", platformNames[platform])
+			}
+		}
+	}
+
+	if len(searchedPlatforms(s)) == 0 {
+		fmt.Fprintln(os.Stderr, "No platform credentials found: set GITHUB_ACCESS_TOKEN or GITLAB_ACCESS_TOKEN, or see the README for other platforms")
+		os.Exit(1)
+	}
+
+	return s
+}
+
+func newSearcher(cfg config) *dorky.Searcher {
+	s := &dorky.Searcher{Org: cfg.orgScope, User: cfg.userScope, MinStars: cfg.starMinFlag, Timeout: time.Duration(cfg.timeoutFlag) * time.Second, Logf: verbosePrint}
+	if searchLimit != nil {
+		s.RateFunc = searchLimit.observe
+	}
+	return s
+}
+
+// allSearchers returns s followed by the Searcher of each GitLab instance.
+func allSearchers(s *dorky.Searcher) []*dorky.Searcher {
+	return append([]*dorky.Searcher{s}, gitlabInstances...)
+}
+
+// searchedPlatforms returns the platforms that have a client in any
+// Searcher, in a stable order.
+func searchedPlatforms(s *dorky.Searcher) []string {
+	if len(gitlabInstances) == 0 {
+		return s.Platforms()
+	}
+
+	var platforms []string
+	for _, platform := range allPlatforms {
+		if platform == platformGitLab || containsString(s.Platforms(), platform) {
+			platforms = append(platforms, platform)
+		}
+	}
+	return platforms
+}
+
+// checkAuth makes one authenticated request per platform and exits if any
+// platform rejects its credentials, rather than letting every search fail.
+func checkAuth(s *dorky.Searcher) {
+	for _, target := range allSearchers(s) {
+		for _, platform := range target.Platforms() {
+			name := platformNames[platform]
+			if target.Instance != "" {
+				name += " at " + target.Instance
+			}
+			verbosePrint("Checking %s credentials...\n", name)
+			err := target.Authenticate(context.Background(), platform)
+			if errors.Is(err, dorky.ErrAuth) {
+				fmt.Fprintf(os.Stderr, "Error: %s token invalid or lacks scope: %s\nFix the credentials or pass -skip-auth-check\n", name, err)
+				os.Exit(1)
+			}
+			if err != nil {
+				fmt.Fprintf(os.Stderr, "Error checking %s credentials: %s\n", name, err)
+				os.Exit(1)
+			}
+		}
+	}
+}
+
+func clientOptions(cfg config) dorky.ClientOptions {
+	return dorky.ClientOptions{
+		Retries: cfg.retriesFlag,
+		Timeout: time.Duration(cfg.timeoutFlag) * time.Second,
+		Logf:    verbosePrint,
+		Debugf:  debugPrint,
+		Proxy:   proxyURL,
+		Delay:   time.Duration(cfg.delayFlag) * time.Millisecond,
+		ETagDir: cfg.etagCacheFlag,
+	}
+}
+
+// readToken returns the token stored in file if one was given, falling back
+// to the first of the environment variables keys that is set.
+func readToken(file, flagName string, keys ...string) (string, error) {
+	if file != "" {
+		data, err := os.ReadFile(file)
+		if err != nil {
+			return "", fmt.Errorf("reading %s: %s", flagName, err)
+		}
+		token := strings.TrimSpace(string(data))
+		if token == "" {
+			return "", fmt.Errorf("token file %s is empty", file)
+		}
+		return token, nil
+	}
+
+	for _, key := range keys {
+		if token := getenv(key); token != "" {
+			return token, nil
+		}
+	}
+	return "", fmt.Errorf("%s environment variable is not set and no %s was given", strings.Join(keys, " or "), flagName)
+}
+
+// createGitHubClient builds a client that rotates between every GitHub token
+// given. -gh-tokens, the token file, and GITHUB_ACCESS_TOKEN or GITHUB_TOKEN
+// may each hold several tokens separated by commas or whitespace.
+func createGitHubClient(cfg config) (*github.Client, error) {
+	token := cfg.ghTokensFlag
+	if token == "" {
+		var err error
+		if token, err = readToken(cfg.ghTokenFile, "-gh-token-file", "GITHUB_ACCESS_TOKEN", "GITHUB_TOKEN"); err != nil {
+			return nil, err
+		}
+	}
+
+	tokens := strings.FieldsFunc(token, func(r rune) bool {
+		return r == ',' || unicode.IsSpace(r)
+	})
+	if len(tokens) > 1 {
+		verbosePrint("Rotating between %d GitHub tokens\n", len(tokens))
+	}
+
+	return dorky.NewGitHubClientWithTokens(tokens, clientOptions(cfg))
+}
+
+// createGitLabClient creates a client for the GitLab instance at baseURL,
+// using the token in the variable gitlabTokenKey names for its host. The
+// first instance falls back to the -gl-token-file, GITLAB_ACCESS_TOKEN, or
+// GITLAB_TOKEN token; the others never do, so one instance's token is not
+// sent to another.
+func createGitLabClient(cfg config, baseURL string, first bool) (*gitlab.Client, error) {
+	if baseURL != "" {
+		if err := dorky.ValidateBaseURL(baseURL); err != nil {
+			return nil, fmt.Errorf("GitLab URL %s", err)
+		}
+	}
+
+	key := gitlabTokenKey(gitlabHost(baseURL))
+	token := getenv(key)
+	if token == "" && !first {
+		return nil, fmt.Errorf("%s environment variable is not set", key)
+	}
+	if token == "" {
+		var err error
+		if token, err = readToken(cfg.glTokenFile, "-gl-token-file", "GITLAB_ACCESS_TOKEN", "GITLAB_TOKEN"); err != nil {
+			return nil, err
+		}
+	}
+
+	return dorky.NewGitLabClient(token, baseURL, clientOptions(cfg))
+}
+
+// gitlabURLs returns the GitLab instances named by -gl-url, or by GITLAB_URL
+// when the flag is not given.
+func gitlabURLs(cfg config) []string {
+	list := cfg.glURLFlag
+	if list == "" {
+		list = getenv("GITLAB_URL")
+	}
+
+	var baseURLs []string
+	for _, baseURL := range strings.Split(list, ",") {
+		if baseURL = strings.TrimSpace(baseURL); baseURL != "" {
+			baseURLs = append(baseURLs, baseURL)
+		}
+	}
+	return baseURLs
+}
+
+// gitlabHost returns the host of a GitLab base URL, or gitlab.com for the
+// default instance.
+func gitlabHost(baseURL string) string {
+	if baseURL == "" {
+		return "gitlab.com"
+	}
+	if u, err := url.Parse(baseURL); err == nil && u.Host != "" {
+		return u.Host
+	}
+	return baseURL
+}
+
+// gitlabTokenKey returns the environment variable holding the token for the
+// GitLab instance at host, such as GITLAB_ACCESS_TOKEN_GITLAB_EXAMPLE_COM.
+func gitlabTokenKey(host string) string {
+	return "GITLAB_ACCESS_TOKEN_" + strings.Map(func(r rune) rune {
+		if r >= 'a' && r <= 'z' {
+			return r - 'a' + 'A'
+		}
+		if r >= 'A' && r <= 'Z' || r >= '0' && r <= '9' {
+			return r
+		}
+		return '_'
+	}, host)
+}
+
+func createBitbucketClient(cfg config) (*dorky.BitbucketClient, error) {
+	username := getenv("BITBUCKET_USERNAME")
+	password := getenv("BITBUCKET_APP_PASSWORD")
+	if username == "" || password == "" {
+		return nil, errors.New("BITBUCKET_USERNAME and BITBUCKET_APP_PASSWORD environment variables are not set")
+	}
+
+	return dorky.NewBitbucketClient(username, password, clientOptions(cfg))
+}
+
+func createGiteaClient(cfg config) (*dorky.GiteaClient, error) {
+	baseURL := getenv("GITEA_URL")
+	if err := dorky.ValidateBaseURL(baseURL); err != nil {
+		return nil, fmt.Errorf("GITEA_URL %s", err)
+	}
+
+	token := getenv("GITEA_TOKEN")
+	if token == "" {
+		return nil, errors.New("GITEA_TOKEN environment variable is not set")
+	}
+
+	return dorky.NewGiteaClient(baseURL, token, clientOptions(cfg))
+}
+
+func createAzureClient(cfg config) (*dorky.AzureDevOpsClient, error) {
+	token := getenv("AZURE_DEVOPS_TOKEN")
+	if token == "" {
+		return nil, errors.New("AZURE_DEVOPS_TOKEN environment variable is not set")
+	}
+
+	return dorky.NewAzureDevOpsClient(cfg.azOrgFlag, token, clientOptions(cfg))
+}
+
+func createSourceHutClient(cfg config) (*dorky.SourceHutClient, error) {
+	token := getenv("SRHT_TOKEN")
+	if token == "" {
+		return nil, errors.New("SRHT_TOKEN environment variable is not set")
+	}
+
+	return dorky.NewSourceHutClient(token, clientOptions(cfg))
+}
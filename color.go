@@ -0,0 +1,56 @@
+package main
+
+import (
+	"io"
+	"os"
+)
+
+const (
+	colorAuto   = "auto"
+	colorAlways = "always"
+	colorNever  = "never"
+
+	colorHeader = "\x1b[1;36m"
+	colorExact  = "\x1b[32m"
+	colorReset  = "\x1b[0m"
+)
+
+// colorEnabled decides whether bullet output is colored. The -s, -q, and
+// -json formats are meant for other programs and are never colored. In auto
+// mode color is used only when writing to a terminal and NO_COLOR is unset.
+func colorEnabled(cfg config) bool {
+	if cfg.simpleFlag || cfg.quietFlag || cfg.jsonFlag || cfg.csvFlag {
+		return false
+	}
+
+	switch cfg.colorFlag {
+	case colorAlways:
+		return true
+	case colorNever:
+		return false
+	}
+
+	if os.Getenv("NO_COLOR") != "" || cfg.outDirFlag != "" {
+		return false
+	}
+	return isTerminal(output)
+}
+
+func isTerminal(w io.Writer) bool {
+	f, ok := w.(*os.File)
+	if !ok {
+		return false
+	}
+	info, err := f.Stat()
+	if err != nil {
+		return false
+	}
+	return info.Mode()&os.ModeCharDevice != 0
+}
+
+func colorize(color, text string) string {
+	if !useColor {
+		return text
+	}
+	return color + text + colorReset
+}
@@ -0,0 +1,137 @@
+package main
+
+import (
+	"errors"
+	"flag"
+	"fmt"
+	"os"
+	"path/filepath"
+	"strings"
+
+	"gopkg.in/yaml.v3"
+)
+
+const defaultConfigFile = ".dorky.yaml"
+
+// fileConfig mirrors the settings that can be given in a config file. Pointer
+// fields distinguish "unset" from zero values so they don't clobber defaults.
+type fileConfig struct {
+	Max        *int     `yaml:"max"`
+	MaxOrgs    *int     `yaml:"max_orgs"`
+	MaxRepos   *int     `yaml:"max_repos"`
+	MaxUsers   *int     `yaml:"max_users"`
+	Threads    *int     `yaml:"threads"`
+	Retries    *int     `yaml:"retries"`
+	Timeout    *int     `yaml:"timeout"`
+	Delay      *int     `yaml:"delay"`
+	Platforms  []string `yaml:"platforms"`
+	Categories []string `yaml:"categories"`
+	Tokens     struct {
+		GitHub               string `yaml:"github"`
+		GitLab               string `yaml:"gitlab"`
+		BitbucketUsername    string `yaml:"bitbucket_username"`
+		BitbucketAppPassword string `yaml:"bitbucket_app_password"`
+		Gitea                string `yaml:"gitea"`
+		AzureDevOps          string `yaml:"azure_devops"`
+		SourceHut            string `yaml:"sourcehut"`
+
+		// GitLabInstances maps the host of each additional GitLab
+		// instance to its token.
+		GitLabInstances map[string]string `yaml:"gitlab_instances"`
+	} `yaml:"tokens"`
+	URLs struct {
+		GitLab string `yaml:"gitlab"`
+		Gitea  string `yaml:"gitea"`
+	} `yaml:"urls"`
+}
+
+// fileEnv holds environment values supplied by the config file. Real
+// environment variables always take precedence over it.
+var fileEnv = map[string]string{}
+
+func getenv(key string) string {
+	if value := os.Getenv(key); value != "" {
+		return value
+	}
+	return fileEnv[key]
+}
+
+// loadConfigFile applies the config file on top of the built-in defaults,
+// leaving any flag given on the command line untouched. A missing file at the
+// default location is ignored; a missing file named by -config is an error.
+func loadConfigFile(cfg *config) error {
+	path := cfg.configFlag
+	if path == "" {
+		home, err := os.UserHomeDir()
+		if err != nil {
+			return nil
+		}
+		path = filepath.Join(home, defaultConfigFile)
+	}
+
+	data, err := os.ReadFile(path)
+	if err != nil {
+		if errors.Is(err, os.ErrNotExist) && cfg.configFlag == "" {
+			return nil
+		}
+		return err
+	}
+
+	var fc fileConfig
+	if err := yaml.Unmarshal(data, &fc); err != nil {
+		return fmt.Errorf("parsing %s: %s", path, err)
+	}
+
+	set := make(map[string]bool)
+	flag.Visit(func(f *flag.Flag) {
+		set[f.Name] = true
+	})
+
+	applyInt := func(name string, dst *int, value *int) {
+		if value != nil && !set[name] {
+			*dst = *value
+		}
+	}
+	applyInt("max", &cfg.maxFlag, fc.Max)
+	applyInt("max-orgs", &cfg.maxOrgsFlag, fc.MaxOrgs)
+	applyInt("max-repos", &cfg.maxReposFlag, fc.MaxRepos)
+	applyInt("max-users", &cfg.maxUsersFlag, fc.MaxUsers)
+	applyInt("threads", &cfg.threadsFlag, fc.Threads)
+	applyInt("retries", &cfg.retriesFlag, fc.Retries)
+	applyInt("timeout", &cfg.timeoutFlag, fc.Timeout)
+	applyInt("delay", &cfg.delayFlag, fc.Delay)
+
+	if !set["platforms"] && !set["gh"] && !set["gl"] && !set["bb"] && !set["gitea"] && !set["az"] && !set["srht"] {
+		for _, platform := range fc.Platforms {
+			if _, ok := platformNames[platform]; !ok {
+				return fmt.Errorf("%s: unknown platform %q", path, platform)
+			}
+		}
+		cfg.platformsFlag = strings.Join(fc.Platforms, ",")
+	}
+
+	if !set["categories"] && !set["o"] && !set["r"] && !set["u"] && !set["code"] && !set["gist"] && !set["topics"] && !set["snippets"] {
+		for _, category := range fc.Categories {
+			if !containsString(allCategories, category) {
+				return fmt.Errorf("%s: unknown category %q", path, category)
+			}
+		}
+		cfg.categoriesFlag = strings.Join(fc.Categories, ",")
+	}
+
+	fileEnv["GITHUB_ACCESS_TOKEN"] = fc.Tokens.GitHub
+	fileEnv["GITLAB_ACCESS_TOKEN"] = fc.Tokens.GitLab
+	fileEnv["BITBUCKET_USERNAME"] = fc.Tokens.BitbucketUsername
+	fileEnv["BITBUCKET_APP_PASSWORD"] = fc.Tokens.BitbucketAppPassword
+	fileEnv["GITEA_TOKEN"] = fc.Tokens.Gitea
+	fileEnv["AZURE_DEVOPS_TOKEN"] = fc.Tokens.AzureDevOps
+	fileEnv["SRHT_TOKEN"] = fc.Tokens.SourceHut
+	fileEnv["GITLAB_URL"] = fc.URLs.GitLab
+	for host, token := range fc.Tokens.GitLabInstances {
+		fileEnv[gitlabTokenKey(host)] = token
+	}
+	fileEnv["GITEA_URL"] = fc.URLs.Gitea
+
+	verbosePrint("Loaded config file %s\n", path)
+	return nil
+}
@@ -0,0 +1,62 @@
+package main
+
+import (
+	"flag"
+	"os"
+	"path/filepath"
+	"testing"
+)
+
+func TestLoadConfigFilePrecedence(t *testing.T) {
+	path := filepath.Join(t.TempDir(), "dorky.yaml")
+	data := "max: 50\nthreads: 8\nplatforms: [gitlab]\ntokens:\n  github: file-github-token\n  gitlab: file-gitlab-token\n"
+	if err := os.WriteFile(path, []byte(data), 0600); err != nil {
+		t.Fatal(err)
+	}
+
+	defer func(saved map[string]string) { fileEnv = saved }(fileEnv)
+	fileEnv = map[string]string{}
+	t.Setenv("GITHUB_ACCESS_TOKEN", "env-github-token")
+	t.Setenv("GITLAB_ACCESS_TOKEN", "")
+
+	cfg := flags
+	cfg.configFlag = path
+
+	// loadConfigFile asks flag.CommandLine which flags were given, so a
+	// fresh set stands in for it, with -max given on the command line.
+	saved := flag.CommandLine
+	t.Cleanup(func() { flag.CommandLine = saved })
+	flag.CommandLine = flag.NewFlagSet("dorky", flag.ContinueOnError)
+	flag.IntVar(&cfg.maxFlag, "max", cfg.maxFlag, "")
+	if err := flag.CommandLine.Parse([]string{"-max", "5"}); err != nil {
+		t.Fatal(err)
+	}
+
+	if err := loadConfigFile(&cfg); err != nil {
+		t.Fatal(err)
+	}
+
+	tests := []struct {
+		name      string
+		got, want interface{}
+	}{
+		{"flag over file", cfg.maxFlag, 5},
+		{"file over default", cfg.threadsFlag, 8},
+		{"file list over default", cfg.platformsFlag, "gitlab"},
+		{"default when unset", cfg.retriesFlag, 3},
+		{"environment over file", getenv("GITHUB_ACCESS_TOKEN"), "env-github-token"},
+		{"file when environment unset", getenv("GITLAB_ACCESS_TOKEN"), "file-gitlab-token"},
+	}
+	for _, tt := range tests {
+		if tt.got != tt.want {
+			t.Errorf("%s: got %v, want %v", tt.name, tt.got, tt.want)
+		}
+	}
+}
+
+func TestLoadConfigFileMissing(t *testing.T) {
+	cfg := config{configFlag: filepath.Join(t.TempDir(), "missing.yaml")}
+	if err := loadConfigFile(&cfg); err == nil {
+		t.Error("loadConfigFile succeeded for a missing -config file, want an error")
+	}
+}
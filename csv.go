@@ -0,0 +1,48 @@
+package main
+
+import (
+	"encoding/csv"
+	"io"
+	"os"
+
+	"github.com/codingo/dorky/pkg/dorky"
+)
+
+// defaultCSVFields are the -csv columns when -output-fields is not given.
+var defaultCSVFields = []string{"platform", "category", "query", "name", "url", "stars"}
+
+// csvWriters holds a CSV writer for each destination written to so far, so
+// each gets its header row once. Callers must hold outputMu.
+var csvWriters = make(map[io.Writer]*csv.Writer)
+
+// writeCSV writes results to w as CSV rows, preceded by a header row the
+// first time w is written to. A file appended to with -append that already
+// holds rows gets no second header.
+func writeCSV(w io.Writer, results []dorky.Result) {
+	cw, ok := csvWriters[w]
+	if !ok {
+		cw = csv.NewWriter(w)
+		csvWriters[w] = cw
+		if !hasContent(w) {
+			cw.Write(outputFields)
+		}
+	}
+
+	for _, r := range results {
+		cw.Write(fieldValues(r))
+	}
+	cw.Flush()
+	if err := cw.Error(); err != nil {
+		errorPrint("Error writing CSV: %s\n", err)
+	}
+}
+
+// hasContent reports whether w is a file that already holds data.
+func hasContent(w io.Writer) bool {
+	f, ok := w.(*os.File)
+	if !ok || !flags.appendFlag {
+		return false
+	}
+	info, err := f.Stat()
+	return err == nil && info.Mode().IsRegular() && info.Size() > 0
+}
@@ -0,0 +1,55 @@
+package main
+
+import (
+	"strings"
+
+	"github.com/codingo/dorky/pkg/dorky"
+)
+
+// guessEmails returns candidate addresses at -domain for a user result:
+// first.last and flast when the user's display name has at least two parts,
+// and username. They follow common corporate patterns and are not checked in
+// any way.
+func guessEmails(r dorky.Result) []string {
+	if !flags.emailsFlag || r.Category != categoryUser {
+		return nil
+	}
+
+	var locals []string
+	add := func(local string) {
+		if local != "" && !containsString(locals, local) {
+			locals = append(locals, local)
+		}
+	}
+
+	var parts []string
+	for _, field := range strings.Fields(r.DisplayName) {
+		if part := emailLocalPart(field); part != "" {
+			parts = append(parts, part)
+		}
+	}
+	if len(parts) >= 2 {
+		first, last := parts[0], parts[len(parts)-1]
+		add(first + "." + last)
+		add(first[:1] + last)
+	}
+	add(emailLocalPart(r.Name))
+
+	emails := make([]string, len(locals))
+	for i, local := range locals {
+		emails[i] = local + "@" + strings.ToLower(flags.domainFlag)
+	}
+	return emails
+}
+
+// emailLocalPart lowercases s and drops everything but ASCII letters, digits,
+// dots, hyphens, and underscores, so it can be used before the @.
+func emailLocalPart(s string) string {
+	var b strings.Builder
+	for _, r := range strings.ToLower(s) {
+		if r >= 'a' && r <= 'z' || r >= '0' && r <= '9' || r == '.' || r == '-' || r == '_' {
+			b.WriteRune(r)
+		}
+	}
+	return strings.Trim(b.String(), ".")
+}
@@ -0,0 +1,74 @@
+package main
+
+import (
+	"fmt"
+	"sort"
+	"strconv"
+	"strings"
+	"time"
+
+	"github.com/codingo/dorky/pkg/dorky"
+)
+
+// resultFields maps each -output-fields name, the same as the -json key, to
+// the text printed for it.
+var resultFields = map[string]func(dorky.Result) string{
+	"platform":     func(r dorky.Result) string { return r.Platform },
+	"category":     func(r dorky.Result) string { return r.Category },
+	"query":        func(r dorky.Result) string { return r.Query },
+	"name":         func(r dorky.Result) string { return r.Name },
+	"url":          func(r dorky.Result) string { return r.URL },
+	"description":  func(r dorky.Result) string { return r.Description },
+	"stars":        func(r dorky.Result) string { return strconv.Itoa(r.Stars) },
+	"fork":         func(r dorky.Result) string { return strconv.FormatBool(r.Fork) },
+	"archived":     func(r dorky.Result) string { return strconv.FormatBool(r.Archived) },
+	"language":     func(r dorky.Result) string { return r.Language },
+	"display_name": func(r dorky.Result) string { return r.DisplayName },
+	"company":      func(r dorky.Result) string { return r.Company },
+	"blog":         func(r dorky.Result) string { return r.Blog },
+	"email":        func(r dorky.Result) string { return r.Email },
+	"public_repos": func(r dorky.Result) string { return strconv.Itoa(r.PublicRepos) },
+	"instance":     func(r dorky.Result) string { return r.Instance },
+	"score":        func(r dorky.Result) string { return strconv.FormatFloat(r.Score, 'f', 3, 64) },
+	"last_active": func(r dorky.Result) string {
+		if r.LastActive.IsZero() {
+			return ""
+		}
+		return r.LastActive.Format(time.RFC3339)
+	},
+}
+
+// parseOutputFields splits a comma-separated -output-fields list, rejecting
+// names resultFields does not know.
+func parseOutputFields(list string) ([]string, error) {
+	var fields []string
+	for _, field := range strings.Split(list, ",") {
+		field = strings.ToLower(strings.TrimSpace(field))
+		if field == "" {
+			continue
+		}
+		if _, ok := resultFields[field]; !ok {
+			known := make([]string, 0, len(resultFields))
+			for name := range resultFields {
+				known = append(known, name)
+			}
+			sort.Strings(known)
+			return nil, fmt.Errorf("unknown field %q, expected one of %s", field, strings.Join(known, ", "))
+		}
+		fields = append(fields, field)
+	}
+	if len(fields) == 0 {
+		return nil, fmt.Errorf("no fields given")
+	}
+	return fields, nil
+}
+
+// fieldValues returns the -output-fields of r in order, or the -csv
+// columns.
+func fieldValues(r dorky.Result) []string {
+	values := make([]string, len(outputFields))
+	for i, field := range outputFields {
+		values[i] = resultFields[field](r)
+	}
+	return values
+}
@@ -0,0 +1,32 @@
+// Package atomicfile writes files that other processes may be reading at the
+// same time, such as the entries of an on-disk cache.
+package atomicfile
+
+import (
+	"os"
+	"path/filepath"
+)
+
+// Write saves data as the file name in dir, creating dir if needed. It writes
+// to a temporary file first and renames it into place, so a concurrent reader
+// never sees a partial file.
+func Write(dir, name string, data []byte) error {
+	if err := os.MkdirAll(dir, 0700); err != nil {
+		return err
+	}
+
+	tmp, err := os.CreateTemp(dir, name+".*.tmp")
+	if err != nil {
+		return err
+	}
+	if _, err := tmp.Write(data); err != nil {
+		tmp.Close()
+		os.Remove(tmp.Name())
+		return err
+	}
+	if err := tmp.Close(); err != nil {
+		os.Remove(tmp.Name())
+		return err
+	}
+	return os.Rename(tmp.Name(), filepath.Join(dir, name))
+}
@@ -0,0 +1,62 @@
+package main
+
+import (
+	"flag"
+	"fmt"
+	"log/slog"
+	"os"
+	"strings"
+)
+
+const (
+	logText = "text"
+	logJSON = "json"
+)
+
+// logger receives all diagnostic output. Results never go through it, so
+// stdout stays clean for pipelines.
+var logger = slog.New(slog.NewTextHandler(os.Stderr, &slog.HandlerOptions{Level: slog.LevelWarn}))
+
+// setupLogger replaces logger with one writing cfg's -log-format to stderr.
+// Errors are always logged unless -q is given, -v adds progress messages, and
+// -v -v adds each API request. With -json the format defaults to JSON, so
+// stderr is as machine-readable as stdout.
+func setupLogger(cfg config) error {
+	level := slog.LevelWarn
+	switch {
+	case cfg.quietFlag:
+		level = slog.LevelError + 1
+	case cfg.verboseFlag > 1:
+		level = slog.LevelDebug
+	case cfg.verboseFlag > 0:
+		level = slog.LevelInfo
+	}
+
+	format := cfg.logFormatFlag
+	if cfg.jsonFlag {
+		explicit := false
+		flag.Visit(func(f *flag.Flag) {
+			explicit = explicit || f.Name == "log-format"
+		})
+		if !explicit {
+			format = logJSON
+		}
+	}
+
+	opts := &slog.HandlerOptions{Level: level}
+	switch format {
+	case logText:
+		logger = slog.New(slog.NewTextHandler(os.Stderr, opts))
+	case logJSON:
+		logger = slog.New(slog.NewJSONHandler(os.Stderr, opts))
+	default:
+		return fmt.Errorf("The -log-format flag must be %s or %s", logText, logJSON)
+	}
+	return nil
+}
+
+// logMessage formats a printf-style message for logger. Messages are written
+// with a trailing newline for the old plain output, which slog adds itself.
+func logMessage(format string, a ...interface{}) string {
+	return strings.TrimSuffix(fmt.Sprintf(format, a...), "\n")
+}
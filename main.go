@@ -2,356 +2,2234 @@ package main
 
 import (
 	"bufio"
+	"bytes"
 	"context"
+	"encoding/json"
 	"errors"
 	"flag"
 	"fmt"
-	"net/http"
+	"io"
+	"math"
+	"net"
+	"net/url"
 	"os"
+	"os/signal"
+	"path"
 	"regexp"
+	"sort"
+	"strconv"
 	"strings"
+	"sync"
+	"sync/atomic"
+	"syscall"
+	"text/template"
+	"time"
+	"unicode/utf8"
+
+	"github.com/codingo/dorky/pkg/dorky"
+	"golang.org/x/net/publicsuffix"
+)
+
+type config struct {
+	categoriesFlag       string
+	orgFlag              bool
+	repoFlag             bool
+	userFlag             bool
+	codeFlag             bool
+	gistFlag             bool
+	topicsFlag           bool
+	snippetFlag          bool
+	colorFlag            string
+	countFlag            bool
+	dryRunFlag           bool
+	minLenFlag           int
+	noMutate             bool
+	cacheFlag            string
+	cacheTTLFlag         time.Duration
+	etagCacheFlag        string
+	noCacheFlag          bool
+	maxFlag              int
+	maxTotalFlag         int
+	maxOrgsFlag          int
+	maxReposFlag         int
+	maxUsersFlag         int
+	cleanFlag            bool
+	seedFlag             bool
+	platformsFlag        string
+	ghOnlyFlag           bool
+	glOnlyFlag           bool
+	bbOnlyFlag           bool
+	giteaFlag            bool
+	azFlag               bool
+	azOrgFlag            string
+	srhtFlag             bool
+	skipAuthFlag         bool
+	ghTokensFlag         string
+	inputFlag            string
+	summaryFlag          bool
+	logFormatFlag        string
+	firstFlag            bool
+	minScoreFlag         float64
+	noCommentsFlag       bool
+	formatFlag           string
+	outputFieldsFlag     string
+	csvFlag              bool
+	seenFileFlag         string
+	webhookFlag          string
+	includeForks         bool
+	excludeForks         bool
+	archivedFlag         bool
+	noArchivedFlag       bool
+	langFlag             string
+	starMinFlag          int
+	expandOrgsFlag       bool
+	emailsFlag           bool
+	resolveFlag          bool
+	domainFlag           string
+	activeSince          string
+	staleBefore          string
+	orgScope             string
+	userScope            string
+	simpleFlag           bool
+	verboseFlag          verbosity
+	containsFlag         stringList
+	jsonFlag             bool
+	threadsFlag          int
+	adaptiveThreadsFlag  bool
+	wordsFlag            string
+	dupesFlag            bool
+	versionFlag          bool
+	retriesFlag          int
+	timeoutFlag          int
+	delayFlag            int
+	urlsFlag             bool
+	plainNamesFlag       bool
+	exactFlag            bool
+	caseSensitiveFlag    bool
+	normalizeUnicodeFlag bool
+	configFlag           string
+	outFlag              string
+	outDirFlag           string
+	appendFlag           bool
+	quietFlag            bool
+	checkFlag            bool
+	permuteFlag          bool
+	maxPermFlag          int
+	affixesFlag          string
+	sortFlag             string
+	filterFlag           string
+	excludeFlag          string
+	proxyFlag            string
+	ghTokenFile          string
+	glTokenFile          string
+	glURLFlag            string
+}
+
+const (
+	platformGitHub    = dorky.PlatformGitHub
+	platformGitLab    = dorky.PlatformGitLab
+	platformBitbucket = dorky.PlatformBitbucket
+	platformGitea     = dorky.PlatformGitea
+	platformAzure     = dorky.PlatformAzure
+	platformSourceHut = dorky.PlatformSourceHut
+
+	categoryOrg     = dorky.CategoryOrg
+	categoryRepo    = dorky.CategoryRepo
+	categoryUser    = dorky.CategoryUser
+	categoryCode    = dorky.CategoryCode
+	categoryGist    = dorky.CategoryGist
+	categoryTopic   = dorky.CategoryTopic
+	categorySnippet = dorky.CategorySnippet
+)
+
+// allCategories lists every category in the order they are searched.
+var allCategories = []string{categoryOrg, categoryRepo, categoryUser, categoryCode, categoryGist, categoryTopic, categorySnippet}
+
+// allPlatforms lists every platform in the order they are searched.
+var allPlatforms = []string{platformGitHub, platformGitLab, platformBitbucket, platformGitea, platformAzure, platformSourceHut}
+
+var platformNames = map[string]string{
+	platformGitHub:    "GitHub",
+	platformGitLab:    "GitLab",
+	platformBitbucket: "Bitbucket",
+	platformGitea:     "Gitea",
+	platformAzure:     "Azure DevOps",
+	platformSourceHut: "SourceHut",
+}
+
+var categoryLabels = map[string]map[string]string{
+	platformGitHub: {
+		categoryOrg:   "GitHub organizations",
+		categoryRepo:  "GitHub repositories",
+		categoryUser:  "GitHub users",
+		categoryCode:  "GitHub code",
+		categoryGist:  "GitHub gists",
+		categoryTopic: "GitHub topics",
+	},
+	platformGitLab: {
+		categoryOrg:     "GitLab groups",
+		categoryRepo:    "GitLab projects",
+		categoryUser:    "GitLab users",
+		categoryTopic:   "GitLab projects with topic",
+		categorySnippet: "GitLab snippets",
+	},
+	platformBitbucket: {
+		categoryOrg:  "Bitbucket workspaces",
+		categoryRepo: "Bitbucket repositories",
+		categoryUser: "Bitbucket users",
+	},
+	platformGitea: {
+		categoryOrg:  "Gitea organizations",
+		categoryRepo: "Gitea repositories",
+		categoryUser: "Gitea users",
+	},
+	platformAzure: {
+		categoryOrg:  "Azure DevOps projects",
+		categoryRepo: "Azure DevOps repositories",
+	},
+	platformSourceHut: {
+		categoryRepo: "SourceHut repositories",
+		categoryUser: "SourceHut users",
+	},
+}
+
+var (
+	flags         = config{}
+	sshRegexp     = regexp.MustCompile(`^[\w.-]+@([^:/\s]+):([^\s]+)$`)
+	spaceRegexp   = regexp.MustCompile(`\s+`)
+	filterRegexp  *regexp.Regexp
+	excludeRegexp *regexp.Regexp
+	formatTmpl    *template.Template
+	outputFields  []string
+	languages     map[string]bool
+	activeAfter   time.Time
+	activeBefore  time.Time
+	proxyURL      *url.URL
+	useColor      bool
+
+	snippetDeniedOnce sync.Once
+	output            io.Writer = os.Stdout
+	outputMu          sync.Mutex
+	seen              = make(map[string]struct{})
+	skippedWords      int
+	wordMeta          = make(map[string]map[string]interface{})
+	lastGroup         string
+	counts            = make(map[string]int)
+	sortPending       []dorky.Result
+	expandedOrgs      = make(map[string]bool)
+	resolvedUsers     = make(map[string]dorky.Result)
+
+	// searchFailures and resultCount decide the exit code once all searches
+	// have finished.
+	searchFailures int32
+	resultCount    int
+
+	// stopSearches cancels every remaining search once -max-total results
+	// have been printed.
+	stopSearches context.CancelFunc = func() {}
+
+	// searchErrors collects failed searches for the report printed at the
+	// end of the run.
+	searchErrors   []searchError
+	searchErrorsMu sync.Mutex
+)
+
+const (
+	exitFound       = 0
+	exitError       = 1
+	exitNoResults   = 2
+	exitInterrupted = 130
+)
+
+func init() {
+	flag.Usage = usage
+	flag.StringVar(&flags.categoriesFlag, "categories", "", "comma-separated categories to search: "+strings.Join(allCategories, ", "))
+	flag.BoolVar(&flags.orgFlag, "o", false, "search for organization names")
+	flag.BoolVar(&flags.repoFlag, "r", false, "search for repository names")
+	flag.BoolVar(&flags.userFlag, "u", false, "search for username matches")
+	flag.BoolVar(&flags.codeFlag, "code", false, "search GitHub code for matching file contents")
+	flag.BoolVar(&flags.topicsFlag, "topics", false, "search GitHub topics and GitLab projects tagged with each word")
+	flag.BoolVar(&flags.snippetFlag, "snippets", false, "search public GitLab snippet titles")
+	flag.BoolVar(&flags.gistFlag, "gist", false, "list the public GitHub gists of each word treated as a username")
+	flag.IntVar(&flags.maxFlag, "max", 10, "maximum total search results per category")
+	flag.IntVar(&flags.maxTotalFlag, "max-total", 0, "stop the run once this many results have been printed, 0 for no limit")
+	flag.IntVar(&flags.maxOrgsFlag, "max-orgs", 0, "maximum organization results, overriding -max")
+	flag.IntVar(&flags.maxReposFlag, "max-repos", 0, "maximum repository results, overriding -max")
+	flag.IntVar(&flags.maxUsersFlag, "max-users", 0, "maximum user results, overriding -max")
+	flag.IntVar(&flags.minLenFlag, "minlen", 2, "skip words shorter than this many characters, including generated ones")
+	flag.BoolVar(&flags.cleanFlag, "c", false, "clean input URLs")
+	flag.BoolVar(&flags.seedFlag, "seed-from-domain", false, "search likely organization and user names derived from each input domain")
+	flag.StringVar(&flags.platformsFlag, "platforms", "", "comma-separated platforms to search: "+strings.Join(allPlatforms, ", ")+" (default: all with credentials)")
+	flag.BoolVar(&flags.ghOnlyFlag, "gh", false, "search only GitHub (deprecated: use -platforms github)")
+	flag.BoolVar(&flags.glOnlyFlag, "gl", false, "search only GitLab (deprecated: use -platforms gitlab)")
+	flag.BoolVar(&flags.bbOnlyFlag, "bb", false, "search only Bitbucket (deprecated: use -platforms bitbucket)")
+	flag.BoolVar(&flags.giteaFlag, "gitea", false, "search only the Gitea/Forgejo instance at GITEA_URL (deprecated: use -platforms gitea)")
+	flag.BoolVar(&flags.azFlag, "az", false, "search only the Azure DevOps organization given by -az-org (deprecated: use -platforms azure)")
+	flag.StringVar(&flags.azOrgFlag, "az-org", "", "Azure DevOps organization name or URL to search, using AZURE_DEVOPS_TOKEN")
+	flag.BoolVar(&flags.srhtFlag, "srht", false, "search only SourceHut (deprecated: use -platforms sourcehut)")
+	flag.BoolVar(&flags.simpleFlag, "s", false, "simple output style for piping to another tool")
+	flag.Var(&flags.verboseFlag, "v", "enable verbose mode; repeat (-v -v) to log each API request")
+	flag.BoolVar(&flags.quietFlag, "q", false, "suppress headers and error messages, printing only results")
+	flag.StringVar(&flags.logFormatFlag, "log-format", logText, "format of diagnostic messages on stderr: text or json")
+	flag.BoolVar(&flags.countFlag, "count", false, "print only the number of matches per platform and category")
+	flag.BoolVar(&flags.summaryFlag, "summary", false, "print a table of match counts per input word at the end of the run")
+	flag.StringVar(&flags.formatFlag, "format", "", "Go text/template for each result line, e.g. '{{.Platform}}:{{.Category}} {{.Name}} {{.URL}}'")
+	flag.StringVar(&flags.outputFieldsFlag, "output-fields", "", "comma-separated fields for each -s line, such as name,url,stars")
+	flag.BoolVar(&flags.jsonFlag, "json", false, "output newline-delimited JSON records")
+	flag.BoolVar(&flags.csvFlag, "csv", false, "output CSV with a header row")
+	flag.StringVar(&flags.webhookFlag, "webhook", "", "POST the results found to this URL when the run ends, formatted for Slack or Discord when it is one of theirs")
+	flag.StringVar(&flags.seenFileFlag, "seen-file", "", "skip results listed in this file by earlier runs, and add this run's results to it")
+	flag.BoolVar(&flags.noMutate, "no-mutate", false, "search each input line exactly as given, without whitespace variants")
+	flag.BoolVar(&flags.permuteFlag, "permute", false, "also search combinations of input words and common suffixes")
+	flag.IntVar(&flags.maxPermFlag, "max-permutations", 1000, "maximum number of words generated by -permute")
+	flag.StringVar(&flags.affixesFlag, "affixes", "", "file of prefix:/suffix: tokens to add to each word")
+	flag.BoolVar(&flags.dryRunFlag, "dry-run", false, "print the queries that would be sent to each platform without sending them")
+	flag.BoolVar(&flags.firstFlag, "first", false, "stop searching a word after its first match on any platform or category")
+	flag.BoolVar(&flags.checkFlag, "check", false, "look each word up directly and print only names that exist")
+	flag.BoolVar(&flags.exactFlag, "exact", false, "only show results whose name exactly matches the query (case-insensitive)")
+	flag.BoolVar(&flags.caseSensitiveFlag, "case-sensitive", false, "treat words and result names differing only in case as distinct")
+	flag.BoolVar(&flags.normalizeUnicodeFlag, "normalize-unicode", false, "fold accents and fullwidth characters in words and result names, so café matches cafe")
+	flag.BoolVar(&flags.includeForks, "include-forks", false, "include forked repositories in results")
+	flag.BoolVar(&flags.excludeForks, "exclude-forks", false, "exclude forked repositories from results (default)")
+	flag.BoolVar(&flags.archivedFlag, "archived", false, "include archived repositories in results")
+	flag.BoolVar(&flags.noArchivedFlag, "no-archived", false, "exclude archived repositories from results (default)")
+	flag.StringVar(&flags.orgScope, "org", "", "only search repositories in this GitHub organization or GitLab group (requires -r)")
+	flag.StringVar(&flags.userScope, "user", "", "only search repositories owned by this GitHub or GitLab user (requires -r)")
+	flag.StringVar(&flags.langFlag, "lang", "", "comma-separated languages to keep repositories in, such as go,python")
+	flag.IntVar(&flags.starMinFlag, "star-min", 0, "only keep repositories with at least this many stars")
+	flag.BoolVar(&flags.resolveFlag, "resolve", false, "look up the full profile of each matched GitHub user")
+	flag.BoolVar(&flags.emailsFlag, "emails", false, "guess email addresses at -domain for each matched user (unverified)")
+	flag.StringVar(&flags.domainFlag, "domain", "", "email domain for -emails, such as acme.com")
+	flag.BoolVar(&flags.expandOrgsFlag, "expand-orgs", false, "list the repositories of each matched GitHub organization and GitLab group, up to -max")
+	flag.StringVar(&flags.activeSince, "active-since", "", "only keep repositories active on or after this date (YYYY-MM-DD)")
+	flag.StringVar(&flags.staleBefore, "stale-before", "", "only keep repositories last active before this date (YYYY-MM-DD)")
+	flag.Float64Var(&flags.minScoreFlag, "min-score", 0, "hide results whose name is less similar to the query than this score, from 0 to 1")
+	flag.StringVar(&flags.filterFlag, "filter", "", "only show results whose name matches this regular expression")
+	flag.StringVar(&flags.excludeFlag, "exclude", "", "hide results whose name matches this regular expression")
+	flag.Var(&flags.containsFlag, "contains", "only show results whose name contains this text, ignoring case; repeat to allow any of several")
+	flag.StringVar(&flags.sortFlag, "sort", "", "sort results by stars, name, updated, or score (default: API order)")
+	flag.BoolVar(&flags.urlsFlag, "urls", false, "output the web URL of each result")
+	flag.BoolVar(&flags.plainNamesFlag, "plain-names", false, "output only the last path segment of each name, without the owner or group")
+	flag.StringVar(&flags.colorFlag, "color", colorAuto, "color bullet output: auto, always, or never")
+	flag.StringVar(&flags.outFlag, "out", "", "write results to a file instead of stdout")
+	flag.StringVar(&flags.outDirFlag, "out-dir", "", "write results to one file per platform and category in this directory")
+	flag.BoolVar(&flags.appendFlag, "append", false, "append to the -out or -out-dir files instead of truncating them")
+	flag.StringVar(&flags.configFlag, "config", "", "path to a YAML config file (default ~/"+defaultConfigFile+")")
+	flag.StringVar(&flags.wordsFlag, "w", "", "read input words from a file instead of stdin")
+	flag.BoolVar(&flags.noCommentsFlag, "no-comments", false, "search input lines starting with # instead of skipping them as comments")
+	flag.StringVar(&flags.inputFlag, "input", inputLines, "input format: lines, json for JSON Lines with a \"word\" field, or json-array for one JSON array of words")
+	flag.StringVar(&flags.ghTokenFile, "gh-token-file", "", "read the GitHub token from a file instead of GITHUB_ACCESS_TOKEN")
+	flag.StringVar(&flags.ghTokensFlag, "gh-tokens", "", "comma-separated GitHub tokens to rotate between")
+	flag.StringVar(&flags.glURLFlag, "gl-url", "", "comma-separated GitLab URLs to search, overriding GITLAB_URL")
+	flag.StringVar(&flags.glTokenFile, "gl-token-file", "", "read the GitLab token from a file instead of GITLAB_ACCESS_TOKEN")
+	flag.StringVar(&flags.cacheFlag, "cache", "", "directory to cache API results in between runs")
+	flag.DurationVar(&flags.cacheTTLFlag, "cache-ttl", 24*time.Hour, "how long cached results stay valid")
+	flag.StringVar(&flags.etagCacheFlag, "etag-cache", "", "directory to store API responses in and revalidate them with conditional requests")
+	flag.BoolVar(&flags.noCacheFlag, "no-cache", false, "ignore cached results, refreshing the -cache directory")
+	flag.BoolVar(&flags.skipAuthFlag, "skip-auth-check", false, "skip validating each platform's credentials before searching")
+	flag.StringVar(&flags.proxyFlag, "proxy", "", "proxy URL for all API requests (default: HTTPS_PROXY/HTTP_PROXY)")
+	flag.IntVar(&flags.threadsFlag, "threads", 5, "number of words to search concurrently")
+	flag.BoolVar(&flags.adaptiveThreadsFlag, "adaptive-threads", false, "search fewer words at once as GitHub's rate limit runs low, up to -threads")
+	flag.IntVar(&flags.retriesFlag, "retries", 3, "number of times to retry a rate-limited request or GitLab server error")
+	flag.IntVar(&flags.timeoutFlag, "timeout", 60, "timeout in seconds for each API request, 0 to disable")
+	flag.IntVar(&flags.delayFlag, "delay", 0, "minimum milliseconds between requests to each platform")
+	flag.BoolVar(&flags.dupesFlag, "allow-dupes", false, "print results already reported for another word")
+	flag.BoolVar(&flags.versionFlag, "version", false, "print the version, commit, and build date and exit")
+}
+
+func main() {
+	flag.Parse()
+
+	// -version needs no search flags or credentials.
+	if flags.versionFlag {
+		printVersion(os.Stdout)
+		os.Exit(0)
+	}
+
+	if err := setupLogger(flags); err != nil {
+		fmt.Fprintln(os.Stderr, err)
+		os.Exit(1)
+	}
+
+	if err := loadConfigFile(&flags); err != nil {
+		fmt.Fprintf(os.Stderr, "Error loading config file: %s\n", err)
+		os.Exit(1)
+	}
+
+	validateFlags(flags)
+
+	var outFile *os.File
+	if flags.outFlag != "" {
+		var err error
+		if outFile, err = openOutputFile(flags.outFlag, flags.appendFlag); err != nil {
+			fmt.Fprintf(os.Stderr, "Error opening output file: %s\n", err)
+			os.Exit(1)
+		}
+		output = outFile
+	}
+	if flags.outDirFlag != "" {
+		if err := os.MkdirAll(flags.outDirFlag, 0755); err != nil {
+			fmt.Fprintf(os.Stderr, "Error creating output directory: %s\n", err)
+			os.Exit(1)
+		}
+	}
+	useColor = colorEnabled(flags)
+	if flags.seenFileFlag != "" && !flags.dryRunFlag {
+		if err := openSeenFile(flags.seenFileFlag); err != nil {
+			fmt.Fprintf(os.Stderr, "Error opening seen file: %s\n", err)
+			os.Exit(1)
+		}
+	}
+
+	// A dry run makes no network requests, so it needs no clients.
+	var s *dorky.Searcher
+	if !flags.dryRunFlag {
+		if flags.adaptiveThreadsFlag {
+			searchLimit = newConcurrency(flags.threadsFlag)
+		}
+		s = createSearcher(flags)
+		if !flags.skipAuthFlag {
+			checkAuth(s)
+		}
+	}
+
+	// The first SIGINT or SIGTERM cancels the searches and keeps what was
+	// found so far; a second one exits immediately.
+	ctx, stop := signal.NotifyContext(context.Background(), os.Interrupt, syscall.SIGTERM)
+	go func() {
+		<-ctx.Done()
+		stop()
+	}()
+
+	verbosePrint("Reading and cleaning words...\n")
+	words := readAndCleanWords(ctx, flags, flag.Args())
+	if ctx.Err() != nil {
+		errorPrint("Interrupted while reading input\n")
+		os.Exit(exitInterrupted)
+	}
+	verbosePrint("Words cleaned.\n")
+
+	if flags.dryRunFlag {
+		printDryRun(words, flags)
+	} else {
+		verbosePrint("Searching platforms...\n")
+		searchCtx, cancel := context.WithCancel(ctx)
+		stopSearches = cancel
+		searchPlatforms(searchCtx, s, words, flags)
+		cancel()
+		if flags.sortFlag != "" {
+			printSorted()
+		}
+		if flags.maxTotalFlag > 0 && resultCount >= flags.maxTotalFlag {
+			verbosePrint("Reached -max-total of %d results, skipping the remaining searches\n", flags.maxTotalFlag)
+		}
+		if ctx.Err() != nil {
+			errorPrint("Interrupted, printing results found so far\n")
+		} else {
+			verbosePrint("Platform search completed.\n")
+		}
+
+		if flags.countFlag {
+			printCounts(s, flags)
+		}
+		if flags.summaryFlag {
+			printSummary(s, flags)
+		}
+		printErrorReport()
+		if flags.webhookFlag != "" {
+			// Sent even after an interrupt, so the results found so far
+			// are not lost.
+			sendWebhook(context.Background(), flags.webhookFlag, webhookResults)
+		}
+	}
+
+	if outFile != nil {
+		if err := outFile.Close(); err != nil {
+			fmt.Fprintf(os.Stderr, "Error closing output file: %s\n", err)
+			os.Exit(exitError)
+		}
+	}
+	if seenFile != nil {
+		if err := seenFile.Close(); err != nil {
+			fmt.Fprintf(os.Stderr, "Error closing seen file: %s\n", err)
+			os.Exit(exitError)
+		}
+	}
+	if err := closeOutDir(); err != nil {
+		fmt.Fprintf(os.Stderr, "Error closing output file: %s\n", err)
+		os.Exit(exitError)
+	}
+
+	if ctx.Err() != nil {
+		os.Exit(exitInterrupted)
+	}
+	os.Exit(exitCode())
+}
+
+func exitCode() int {
+	if flags.dryRunFlag {
+		return exitFound
+	}
+
+	if atomic.LoadInt32(&searchFailures) > 0 {
+		return exitError
+	}
+
+	outputMu.Lock()
+	defer outputMu.Unlock()
+	if resultCount == 0 {
+		return exitNoResults
+	}
+	return exitFound
+}
+
+func validateFlags(cfg config) {
+	categories := categorySet(cfg)
+	for category := range categories {
+		if !containsString(allCategories, category) {
+			fmt.Fprintf(os.Stderr, "Unknown category %q in -categories; expected one of %s\n", category, strings.Join(allCategories, ", "))
+			os.Exit(1)
+		}
+	}
+
+	if len(categories) == 0 {
+		fmt.Fprintln(os.Stderr, "At least one category must be given with -categories or a search flag (-o, -r, -u, -code, -gist, -topics, or -snippets)")
+		os.Exit(1)
+	}
+
+	if cfg.checkFlag && (categories[categoryCode] || categories[categoryGist] || categories[categoryTopic] || categories[categorySnippet]) {
+		fmt.Fprintln(os.Stderr, "The -check flag can only be used with -o, -r, and -u")
+		os.Exit(1)
+	}
+
+	if cfg.orgScope != "" && cfg.userScope != "" {
+		fmt.Fprintln(os.Stderr, "The -org and -user flags cannot be used together")
+		os.Exit(1)
+	}
+
+	if scope := scopeFlagName(cfg); scope != "" && !categories[categoryRepo] {
+		fmt.Fprintf(os.Stderr, "The %s flag only restricts repository searches, so it requires -r\n", scope)
+		os.Exit(1)
+	}
+
+	if cfg.emailsFlag != (cfg.domainFlag != "") {
+		fmt.Fprintln(os.Stderr, "The -emails and -domain flags must be used together")
+		os.Exit(1)
+	}
+	if cfg.domainFlag != "" && (!strings.Contains(cfg.domainFlag, ".") || strings.ContainsAny(cfg.domainFlag, "@/ ")) {
+		fmt.Fprintf(os.Stderr, "The -domain flag must be a domain name such as acme.com, not %q\n", cfg.domainFlag)
+		os.Exit(1)
+	}
+	if cfg.resolveFlag && !categories[categoryUser] {
+		fmt.Fprintln(os.Stderr, "The -resolve flag looks up matched users, so it requires -u")
+		os.Exit(1)
+	}
+
+	if cfg.emailsFlag && !categories[categoryUser] {
+		fmt.Fprintln(os.Stderr, "The -emails flag guesses addresses for matched users, so it requires -u")
+		os.Exit(1)
+	}
+
+	if cfg.expandOrgsFlag && !categories[categoryOrg] {
+		fmt.Fprintln(os.Stderr, "The -expand-orgs flag lists the repositories of matched organizations, so it requires -o")
+		os.Exit(1)
+	}
+
+	if cfg.starMinFlag < 0 {
+		fmt.Fprintln(os.Stderr, "The -star-min flag cannot be negative")
+		os.Exit(1)
+	}
+
+	if cfg.maxFlag < 1 {
+		fmt.Fprintln(os.Stderr, "The -max flag must be at least 1")
+		os.Exit(1)
+	}
+
+	if cfg.maxOrgsFlag < 0 || cfg.maxReposFlag < 0 || cfg.maxUsersFlag < 0 {
+		fmt.Fprintln(os.Stderr, "The -max-orgs, -max-repos, and -max-users flags cannot be negative")
+		os.Exit(1)
+	}
+
+	if cfg.threadsFlag < 1 {
+		fmt.Fprintln(os.Stderr, "The -threads flag must be at least 1")
+		os.Exit(1)
+	}
+
+	if cfg.retriesFlag < 0 {
+		fmt.Fprintln(os.Stderr, "The -retries flag cannot be negative")
+		os.Exit(1)
+	}
+
+	if cfg.maxTotalFlag < 0 {
+		fmt.Fprintln(os.Stderr, "The -max-total flag cannot be negative")
+		os.Exit(1)
+	}
+
+	if cfg.delayFlag < 0 {
+		fmt.Fprintln(os.Stderr, "The -delay flag cannot be negative")
+		os.Exit(1)
+	}
+
+	if cfg.timeoutFlag < 0 {
+		fmt.Fprintln(os.Stderr, "The -timeout flag cannot be negative")
+		os.Exit(1)
+	}
+
+	selected := selectedPlatforms(cfg)
+	for platform := range selected {
+		if _, ok := platformNames[platform]; !ok {
+			fmt.Fprintf(os.Stderr, "Unknown platform %q in -platforms; expected one of %s\n", platform, strings.Join(allPlatforms, ", "))
+			os.Exit(1)
+		}
+	}
+
+	if selected[platformGitea] {
+		if err := dorky.ValidateBaseURL(getenv("GITEA_URL")); err != nil {
+			fmt.Fprintf(os.Stderr, "Searching Gitea requires a valid GITEA_URL: GITEA_URL %s\n", err)
+			os.Exit(1)
+		}
+	}
+
+	if selected[platformAzure] && cfg.azOrgFlag == "" {
+		fmt.Fprintln(os.Stderr, "Searching Azure DevOps requires -az-org")
+		os.Exit(1)
+	}
+
+	if cfg.outFlag != "" && cfg.outDirFlag != "" {
+		fmt.Fprintln(os.Stderr, "The -out and -out-dir flags cannot be used together")
+		os.Exit(1)
+	}
+
+	if cfg.appendFlag && cfg.outFlag == "" && cfg.outDirFlag == "" {
+		fmt.Fprintln(os.Stderr, "The -append flag requires -out or -out-dir")
+		os.Exit(1)
+	}
+
+	if cfg.noMutate && cfg.permuteFlag {
+		fmt.Fprintln(os.Stderr, "The -no-mutate and -permute flags cannot be used together")
+		os.Exit(1)
+	}
+
+	if cfg.cacheTTLFlag < 0 {
+		fmt.Fprintln(os.Stderr, "The -cache-ttl flag cannot be negative")
+		os.Exit(1)
+	}
+
+	if cfg.minLenFlag < 0 {
+		fmt.Fprintln(os.Stderr, "The -minlen flag cannot be negative")
+		os.Exit(1)
+	}
+
+	if cfg.maxPermFlag < 0 {
+		fmt.Fprintln(os.Stderr, "The -max-permutations flag cannot be negative")
+		os.Exit(1)
+	}
+
+	if cfg.quietFlag && cfg.verboseFlag > 0 {
+		fmt.Fprintln(os.Stderr, "The -q and -v flags cannot be used together")
+		os.Exit(1)
+	}
+
+	if cfg.simpleFlag && cfg.jsonFlag {
+		fmt.Fprintln(os.Stderr, "The -s and -json flags cannot be used together")
+		os.Exit(1)
+	}
+
+	if cfg.formatFlag != "" && (cfg.simpleFlag || cfg.jsonFlag || cfg.countFlag) {
+		fmt.Fprintln(os.Stderr, "The -format flag cannot be used with -s, -json, or -count")
+		os.Exit(1)
+	}
+
+	if cfg.csvFlag && (cfg.simpleFlag || cfg.jsonFlag || cfg.formatFlag != "" || cfg.countFlag || cfg.summaryFlag) {
+		fmt.Fprintln(os.Stderr, "The -csv flag cannot be used with -s, -json, -format, -count, or -summary")
+		os.Exit(1)
+	}
+
+	if cfg.webhookFlag != "" {
+		if err := dorky.ValidateBaseURL(cfg.webhookFlag); err != nil {
+			fmt.Fprintf(os.Stderr, "The -webhook flag %s\n", err)
+			os.Exit(1)
+		}
+	}
+
+	if cfg.outputFieldsFlag != "" && !cfg.simpleFlag && !cfg.csvFlag {
+		fmt.Fprintln(os.Stderr, "The -output-fields flag selects the columns of -s or -csv output, so it requires one of them")
+		os.Exit(1)
+	}
+
+	if cfg.countFlag && cfg.jsonFlag {
+		fmt.Fprintln(os.Stderr, "The -count and -json flags cannot be used together")
+		os.Exit(1)
+	}
+
+	if cfg.summaryFlag && cfg.jsonFlag {
+		fmt.Fprintln(os.Stderr, "The -summary and -json flags cannot be used together")
+		os.Exit(1)
+	}
+
+	switch cfg.colorFlag {
+	case colorAuto, colorAlways, colorNever:
+	default:
+		fmt.Fprintf(os.Stderr, "The -color flag must be one of %s, %s, or %s\n", colorAuto, colorAlways, colorNever)
+		os.Exit(1)
+	}
+
+	switch cfg.inputFlag {
+	case inputLines, inputJSON, inputJSONArray:
+	default:
+		fmt.Fprintf(os.Stderr, "The -input flag must be %s, %s, or %s\n", inputLines, inputJSON, inputJSONArray)
+		os.Exit(1)
+	}
+
+	switch cfg.sortFlag {
+	case "", sortStars, sortName, sortUpdated, sortScore:
+	default:
+		fmt.Fprintf(os.Stderr, "The -sort flag must be one of %s, %s, %s, or %s\n", sortStars, sortName, sortUpdated, sortScore)
+		os.Exit(1)
+	}
+
+	if cfg.includeForks && cfg.excludeForks {
+		fmt.Fprintln(os.Stderr, "The -include-forks and -exclude-forks flags cannot be used together")
+		os.Exit(1)
+	}
+
+	if cfg.archivedFlag && cfg.noArchivedFlag {
+		fmt.Fprintln(os.Stderr, "The -archived and -no-archived flags cannot be used together")
+		os.Exit(1)
+	}
+
+	if cfg.minScoreFlag < 0 || cfg.minScoreFlag > 1 {
+		fmt.Fprintln(os.Stderr, "The -min-score flag must be between 0 and 1")
+		os.Exit(1)
+	}
+
+	var err error
+	if cfg.formatFlag != "" {
+		if formatTmpl, err = parseFormat(cfg.formatFlag); err != nil {
+			fmt.Fprintf(os.Stderr, "Invalid -format template: %s\n", err)
+			os.Exit(1)
+		}
+	}
+
+	if cfg.outputFieldsFlag != "" {
+		if outputFields, err = parseOutputFields(cfg.outputFieldsFlag); err != nil {
+			fmt.Fprintf(os.Stderr, "Invalid -output-fields: %s\n", err)
+			os.Exit(1)
+		}
+	} else if cfg.csvFlag {
+		outputFields = defaultCSVFields
+	}
+
+	if cfg.langFlag != "" {
+		languages = make(map[string]bool)
+		for _, lang := range strings.Split(cfg.langFlag, ",") {
+			if lang = strings.TrimSpace(lang); lang != "" {
+				languages[strings.ToLower(lang)] = true
+			}
+		}
+		if len(languages) == 0 {
+			fmt.Fprintln(os.Stderr, "The -lang flag must name at least one language")
+			os.Exit(1)
+		}
+	}
+
+	if cfg.activeSince != "" {
+		if activeAfter, err = time.Parse(dateLayout, cfg.activeSince); err != nil {
+			fmt.Fprintf(os.Stderr, "The -active-since flag must be a date such as 2023-01-01, got %q\n", cfg.activeSince)
+			os.Exit(1)
+		}
+	}
+
+	if cfg.staleBefore != "" {
+		if activeBefore, err = time.Parse(dateLayout, cfg.staleBefore); err != nil {
+			fmt.Fprintf(os.Stderr, "The -stale-before flag must be a date such as 2023-01-01, got %q\n", cfg.staleBefore)
+			os.Exit(1)
+		}
+	}
+
+	if !activeAfter.IsZero() && !activeBefore.IsZero() && !activeAfter.Before(activeBefore) {
+		fmt.Fprintln(os.Stderr, "The -active-since date must be before the -stale-before date")
+		os.Exit(1)
+	}
+
+	if cfg.filterFlag != "" {
+		if filterRegexp, err = regexp.Compile(cfg.filterFlag); err != nil {
+			fmt.Fprintf(os.Stderr, "Invalid -filter expression: %s\n", err)
+			os.Exit(1)
+		}
+	}
+
+	if cfg.excludeFlag != "" {
+		if excludeRegexp, err = regexp.Compile(cfg.excludeFlag); err != nil {
+			fmt.Fprintf(os.Stderr, "Invalid -exclude expression: %s\n", err)
+			os.Exit(1)
+		}
+	}
+
+	if cfg.proxyFlag != "" {
+		if proxyURL, err = url.Parse(cfg.proxyFlag); err != nil || proxyURL.Scheme == "" || proxyURL.Host == "" {
+			fmt.Fprintf(os.Stderr, "The -proxy flag must be a URL such as http://proxy.example.com:8080, got %q\n", cfg.proxyFlag)
+			os.Exit(1)
+		}
+	}
+	verbosePrint("Flags validated.\n")
+}
+
+func openOutputFile(name string, appendMode bool) (*os.File, error) {
+	mode := os.O_CREATE | os.O_WRONLY | os.O_TRUNC
+	if appendMode {
+		mode = os.O_CREATE | os.O_WRONLY | os.O_APPEND
+	}
+	return os.OpenFile(name, mode, 0644)
+}
+
+// verbosity is a boolean-style flag that counts how many times it was given.
+type verbosity int
+
+func (v *verbosity) String() string {
+	return strconv.Itoa(int(*v))
+}
+
+func (v *verbosity) Set(value string) error {
+	on, err := strconv.ParseBool(value)
+	if err != nil {
+		return err
+	}
+	if on {
+		*v++
+	} else {
+		*v = 0
+	}
+	return nil
+}
+
+func (v *verbosity) IsBoolFlag() bool {
+	return true
+}
+
+// stringList is a flag that collects every value it is given.
+type stringList []string
+
+func (l *stringList) String() string {
+	return strings.Join(*l, ",")
+}
+
+func (l *stringList) Set(value string) error {
+	*l = append(*l, value)
+	return nil
+}
+
+// verbosePrint logs progress at info level, shown with -v.
+func verbosePrint(format string, a ...interface{}) {
+	logger.Info(logMessage(format, a...))
+}
+
+// debugPrint logs API request details at debug level, shown with -v -v.
+func debugPrint(format string, a ...interface{}) {
+	logger.Debug(logMessage(format, a...))
+}
+
+// errorPrint logs a non-fatal error, shown unless -q was given.
+func errorPrint(format string, a ...interface{}) {
+	logger.Error(logMessage(format, a...))
+}
+
+// wordList holds the words to search in the order they were first added:
+// input order, then affixes, then permutations. The set only removes
+// duplicates, so runs over the same input search in the same order. It maps
+// each word's caseKey to the word as listed.
+type wordList struct {
+	words []string
+	set   map[string]string
+}
+
+func newWordList() *wordList {
+	return &wordList{set: make(map[string]string)}
+}
+
+// caseKey returns the form of s used to find duplicate words and results:
+// lowercased unless -case-sensitive was given, since platform names are
+// case-insensitive, and folded with -normalize-unicode.
+func caseKey(s string) string {
+	s = normalizeUnicode(s)
+	if flags.caseSensitiveFlag {
+		return s
+	}
+	return strings.ToLower(s)
+}
+
+func (l *wordList) len() int {
+	return len(l.words)
+}
+
+func readAndCleanWords(ctx context.Context, cfg config, args []string) *wordList {
+	words := newWordList()
+
+	var inputs []string
+	if len(args) > 0 {
+		for _, word := range args {
+			inputs = append(inputs, processWord(word, words, cfg))
+		}
+	} else if cfg.wordsFlag != "" {
+		file, err := os.Open(cfg.wordsFlag)
+		if err != nil {
+			fmt.Fprintf(os.Stderr, "Error opening wordlist: %s\n", err)
+			os.Exit(1)
+		}
+		defer file.Close()
+
+		inputs = scanWords(ctx, file, words, cfg)
+	} else {
+		inputs = scanWords(ctx, os.Stdin, words, cfg)
+	}
+
+	if cfg.affixesFlag != "" {
+		prefixes, suffixes, err := loadAffixes(cfg.affixesFlag)
+		if err != nil {
+			fmt.Fprintf(os.Stderr, "Error reading affixes: %s\n", err)
+			os.Exit(1)
+		}
+		added := applyAffixes(words, prefixes, suffixes)
+		verbosePrint("Generated %d affixed words.\n", added)
+	}
+
+	if cfg.permuteFlag {
+		generated := permutations(inputs, cfg.maxPermFlag)
+		for _, word := range generated {
+			addWord(words, word)
+		}
+		addPermutationSources(inputs, generated)
+		verbosePrint("Generated %d permutations.\n", len(generated))
+	}
+
+	if skippedWords > 0 {
+		verbosePrint("Skipped %d empty words.\n", skippedWords)
+	}
+
+	// Applied last so short fragments produced by mutation are dropped too.
+	if dropped := dropShortWords(words, cfg.minLenFlag); dropped > 0 {
+		verbosePrint("Dropped %d words shorter than %d characters.\n", dropped, cfg.minLenFlag)
+	}
+
+	return words
+}
+
+const (
+	inputLines     = "lines"
+	inputJSON      = "json"
+	inputJSONArray = "json-array"
+)
+
+// scanWords reads the input words from r in the format set by -input. It
+// stops early, returning the words read so far, once ctx is cancelled.
+func scanWords(ctx context.Context, r io.Reader, words *wordList, cfg config) []string {
+	switch cfg.inputFlag {
+	case inputJSON:
+		return scanJSONWords(ctx, r, words, cfg)
+	case inputJSONArray:
+		return decodeJSONArrayWords(ctx, r, words, cfg)
+	}
+
+	var inputs []string
+	scanner := newInputScanner(r)
+	scanner.Split(scanLongLines)
+
+	for ctx.Err() == nil && scanner.Scan() {
+		// A line this long is a pasted list rather than one word.
+		fields := []string{scanner.Text()}
+		if len(fields[0]) > longLine {
+			fields = strings.Fields(fields[0])
+		}
+		for _, field := range fields {
+			word := strings.TrimSpace(field)
+			if strings.HasPrefix(word, "#") && !cfg.noCommentsFlag {
+				continue
+			}
+			inputs = append(inputs, processWord(word, words, cfg))
+		}
+	}
+	checkScannerError(scanner)
+
+	return inputs
+}
+
+// scanJSONWords reads one JSON object per line, searching its "word" field.
+// The object's other fields are kept in wordMeta for every query generated
+// from the word, so -json output can carry them through.
+func scanJSONWords(ctx context.Context, r io.Reader, words *wordList, cfg config) []string {
+	var inputs []string
+	scanner := newInputScanner(r)
+
+	for line := 1; ctx.Err() == nil && scanner.Scan(); line++ {
+		if strings.TrimSpace(scanner.Text()) == "" {
+			skippedWords++
+			continue
+		}
+
+		var meta map[string]interface{}
+		if err := json.Unmarshal(scanner.Bytes(), &meta); err != nil {
+			fmt.Fprintf(os.Stderr, "Error reading input: line %d: %s\n", line, err)
+			os.Exit(1)
+		}
+		word, ok := meta["word"].(string)
+		if !ok {
+			fmt.Fprintf(os.Stderr, "Error reading input: line %d has no string \"word\" field\n", line)
+			os.Exit(1)
+		}
+		delete(meta, "word")
+
+		word = strings.TrimSpace(word)
+		input := processWord(word, words, cfg)
+		inputs = append(inputs, input)
+		if input != "" && len(meta) > 0 {
+			for _, w := range inputWords(word, cfg) {
+				for _, query := range wordQueries(w, cfg) {
+					wordMeta[query] = meta
+				}
+			}
+		}
+	}
+	checkScannerError(scanner)
+
+	return inputs
+}
+
+// decodeJSONArrayWords reads the whole input as one JSON array of strings,
+// such as ["acme","globex"], and searches each element.
+func decodeJSONArrayWords(ctx context.Context, r io.Reader, words *wordList, cfg config) []string {
+	decoder := json.NewDecoder(r)
+	var list []string
+	err := decoder.Decode(&list)
+	if err == nil && decoder.More() {
+		err = errors.New("unexpected data after the array")
+	}
+	if err != nil {
+		fmt.Fprintf(os.Stderr, "Error reading input: expected one JSON array of strings: %s\n", err)
+		os.Exit(1)
+	}
+
+	var inputs []string
+	for _, word := range list {
+		if ctx.Err() != nil {
+			break
+		}
+		inputs = append(inputs, processWord(strings.TrimSpace(word), words, cfg))
+	}
+	return inputs
+}
 
-	"github.com/google/go-github/v38/github"
-	"github.com/xanzy/go-gitlab"
-	"golang.org/x/oauth2"
-	"golang.org/x/time/rate"
-)
+// processWord adds the words searched for an input word and their whitespace
+// variants to words, returning the first, cleaned input word.
+func processWord(word string, words *wordList, cfg config) string {
+	if strings.TrimSpace(word) == "" {
+		skippedWords++
+		return ""
+	}
 
-type config struct {
-	orgFlag      bool
-	repoFlag     bool
-	userFlag     bool
-	maxFlag      int
-	cleanFlag    bool
-	ghOnlyFlag   bool
-	glOnlyFlag   bool
-	simpleFlag   bool
-	verboseFlag  bool
+	source := word
+	if _, exists := summary[source]; !exists {
+		summary[source] = make(map[string]int)
+		sourceWords = append(sourceWords, source)
+	}
+
+	inputs := inputWords(word, cfg)
+	for _, input := range inputs {
+		for _, query := range wordQueries(input, cfg) {
+			addSources(addWord(words, query), source)
+		}
+	}
+
+	return inputs[0]
 }
 
-var (
-	flags       = config{}
-	urlRegexp   = regexp.MustCompile(`^https?://(?:www\.)?([^/]+)`)
-	spaceRegexp = regexp.MustCompile(`\s+`)
-)
+// inputWords returns the words searched for an input word: its domain seeds
+// with -seed-from-domain, otherwise the word itself, cleaned with -c. Either
+// is folded with -normalize-unicode first.
+func inputWords(word string, cfg config) []string {
+	word = normalizeUnicode(word)
+	if cfg.seedFlag {
+		if seeds := domainSeeds(word); len(seeds) > 0 {
+			return seeds
+		}
+	}
+	if cfg.cleanFlag {
+		word = cleanWord(word)
+	}
+	return []string{word}
+}
 
-func init() {
-	flag.BoolVar(&flags.orgFlag, "o", false, "search for organization names")
-	flag.BoolVar(&flags.repoFlag, "r", false, "search for repository names")
-	flag.BoolVar(&flags.userFlag, "u", false, "search for username matches")
-	flag.IntVar(&flags.maxFlag, "max", 10, "maximum search results per category")
-	flag.BoolVar(&flags.cleanFlag, "c", false, "clean input URLs")
-	flag.BoolVar(&flags.ghOnlyFlag, "gh", false, "search only GitHub")
-	flag.BoolVar(&flags.glOnlyFlag, "gl", false, "search only GitLab")
-	flag.BoolVar(&flags.simpleFlag, "s", false, "simple output style for piping to another tool")
-	flag.BoolVar(&flags.verboseFlag, "v", false, "enable verbose mode")
+// wordQueries returns the queries searched for a cleaned input word.
+func wordQueries(word string, cfg config) []string {
+	if cfg.noMutate {
+		return []string{word}
+	}
+	return wordVariants(word)
 }
 
-func main() {
-	flag.Parse()
-	validateFlags(flags)
+// addWord adds word as a query candidate unless it is already listed,
+// returning the word as listed, which may differ from word in case. Empty and
+// whitespace-only words would waste an API call, so they are counted and
+// dropped.
+func addWord(words *wordList, word string) string {
+	if strings.TrimSpace(word) == "" {
+		skippedWords++
+		return ""
+	}
+	key := caseKey(word)
+	if listed, exists := words.set[key]; exists {
+		return listed
+	}
+	words.set[key] = word
+	words.words = append(words.words, word)
+	return word
+}
 
-	verbosePrint("Reading and cleaning words...\n")
-	words := readAndCleanWords(flags, flag.Args())
-	verbosePrint("Words cleaned.\n")
+const (
+	// maxLineLength is the most of one input line held in memory, well past
+	// bufio.Scanner's 64KB default.
+	maxLineLength = 1 << 20
+
+	// longLine is the length past which a plain input line is split on
+	// whitespace into several words.
+	longLine = 4096
+)
 
-	verbosePrint("Searching platforms...\n")
-	searchPlatforms(words, flags)
-	verbosePrint("Platform search completed.\n")
+// newInputScanner returns a scanner for r that accepts lines up to
+// maxLineLength.
+func newInputScanner(r io.Reader) *bufio.Scanner {
+	scanner := bufio.NewScanner(r)
+	scanner.Buffer(make([]byte, 64*1024), maxLineLength)
+	return scanner
 }
 
-func validateFlags(cfg config) {
-	if !(cfg.orgFlag || cfg.repoFlag || cfg.userFlag) {
-		fmt.Println("At least one search flag (-o, -r, or -u) must be specified")
-		os.Exit(1)
+// scanLongLines is bufio.ScanLines, except that a line longer than
+// maxLineLength is returned in pieces, broken at the last space or tab
+// before the limit where there is one, instead of failing with
+// bufio.ErrTooLong.
+func scanLongLines(data []byte, atEOF bool) (advance int, token []byte, err error) {
+	if advance, token, err = bufio.ScanLines(data, atEOF); advance > 0 || token != nil || err != nil {
+		return advance, token, err
 	}
-	verbosePrint("Flags validated.\n")
+	if len(data) < maxLineLength {
+		return 0, nil, nil
+	}
+	if i := bytes.LastIndexAny(data[:maxLineLength], " \t"); i > 0 {
+		return i + 1, data[:i], nil
+	}
+	return maxLineLength, data[:maxLineLength], nil
 }
 
-func verbosePrint(format string, a ...interface{}) {
-	if flags.verboseFlag {
-		fmt.Printf(format, a...)
+func checkScannerError(scanner *bufio.Scanner) {
+	if err := scanner.Err(); err != nil {
+		fmt.Fprintf(os.Stderr, "Error reading input: %s\n", err)
+		os.Exit(1)
 	}
 }
 
-func readAndCleanWords(cfg config, args []string) map[string]struct{} {
-	words := make(map[string]struct{})
+// The searcher's clients are created once in main so their rate limiters
+// persist for the whole run.
+func searchPlatforms(ctx context.Context, s *dorky.Searcher, words *wordList, cfg config) {
+	queue := make(chan string)
+
+	var wg sync.WaitGroup
+	for i := 0; i < cfg.threadsFlag; i++ {
+		wg.Add(1)
+		go func() {
+			defer wg.Done()
+			for word := range queue {
+				// With -adaptive-threads some workers wait here while
+				// the rate limit is low.
+				if !searchLimit.acquire(ctx) {
+					continue
+				}
+				searchWord(ctx, s, word, cfg)
+				searchLimit.release()
+			}
+		}()
+	}
 
-	if len(args) > 0 {
-		for _, word := range args {
-			processWord(word, words, cfg)
+feed:
+	for _, word := range words.words {
+		select {
+		case queue <- word:
+		case <-ctx.Done():
+			break feed
+		}
+	}
+	close(queue)
+
+	wg.Wait()
+}
+
+// searchWord searches every enabled platform and category for word. With
+// -first it stops at the first match.
+func searchWord(ctx context.Context, s *dorky.Searcher, word string, cfg config) {
+	for _, target := range allSearchers(s) {
+		for _, platform := range target.Platforms() {
+			if !searchPlatformWord(ctx, target, platform, word, cfg) {
+				return
+			}
 		}
+	}
+}
+
+// searchPlatformWord searches every enabled category of one platform for
+// word. It returns false once the remaining searches for word should be
+// skipped, because the run was interrupted or -first found a match.
+func searchPlatformWord(ctx context.Context, s *dorky.Searcher, platform, word string, cfg config) bool {
+	name := platformNames[platform]
+	if s.Instance != "" {
+		name += " at " + s.Instance
+	}
+	if cfg.checkFlag {
+		verbosePrint("Checking %s for word: %s\n", name, word)
 	} else {
-		scanner := bufio.NewScanner(os.Stdin)
+		verbosePrint("Searching %s for word: %s\n", name, word)
+	}
 
-		for scanner.Scan() {
-			word := strings.TrimSpace(scanner.Text())
-			processWord(word, words, cfg)
+	for _, category := range selectedCategories(cfg) {
+		if ctx.Err() != nil {
+			return false
+		}
+		if !dorky.Supported(platform, category) {
+			continue
+		}
+		var found int
+		if cfg.checkFlag {
+			found = checkCategory(ctx, s, platform, category, word)
+		} else {
+			found = searchCategory(ctx, s, platform, category, word, categoryMax(cfg, category))
+		}
+		if cfg.firstFlag && found > 0 {
+			verbosePrint("Found a match for word: %s, skipping its remaining searches\n", word)
+			return false
 		}
-		checkScannerError(scanner)
 	}
+	return true
+}
 
-	return words
+// categoryMax returns the result limit for category: its own -max-* flag
+// when set, otherwise -max.
+func categoryMax(cfg config, category string) int {
+	limit := 0
+	switch category {
+	case categoryOrg:
+		limit = cfg.maxOrgsFlag
+	case categoryRepo:
+		limit = cfg.maxReposFlag
+	case categoryUser:
+		limit = cfg.maxUsersFlag
+	}
+	if limit == 0 {
+		return cfg.maxFlag
+	}
+	return limit
 }
 
-func processWord(word string, words map[string]struct{}, cfg config) {
-	if cfg.cleanFlag {
-		word = cleanWord(word)
+// searchCategory prints results page by page as they arrive, unless -sort
+// needs the full set first, and returns how many were printed. If the run is
+// interrupted, the pages collected so far are printed instead of being lost.
+// With -first the search is cancelled as soon as a result is printed.
+func searchCategory(ctx context.Context, s *dorky.Searcher, platform, category, query string, maxResults int) int {
+	searchCtx, cancel := context.WithCancel(ctx)
+	defer cancel()
+
+	var streamed bool
+	var printed int
+	var collected, matched []dorky.Result
+	defer func() {
+		expandOrgs(ctx, s, platform, category, matched)
+	}()
+	page := func(results []dorky.Result) {
+		if flags.sortFlag != "" {
+			collected = append(collected, results...)
+			return
+		}
+		streamed = true
+		results = resolveUsers(ctx, s, filterResults(query, results))
+		matched = append(matched, results...)
+		printed += printResults(platform, category, query, results)
+		if flags.firstFlag && printed > 0 {
+			cancel()
+		}
+	}
+
+	results, err := cachedSearch("search", platform, s.Instance, category, query, maxResults, func() ([]dorky.Result, error) {
+		return s.SearchStream(searchCtx, platform, category, query, maxResults, page)
+	})
+	if err != nil && searchCtx.Err() != nil {
+		results, err = collected, nil
+	}
+	if errors.Is(err, dorky.ErrSnippetSearchDenied) {
+		// The instance refuses every snippet search, so report it once
+		// rather than for each word.
+		snippetDeniedOnce.Do(func() {
+			printSearchError(platform, category, query, err)
+		})
+		return 0
+	}
+	if err != nil {
+		printSearchError(platform, category, query, err)
+
+		// Pages that arrived before the error are still valid results. They
+		// have been printed already unless -sort held them back.
+		if streamed || len(collected) == 0 {
+			return printed
+		}
+		results = collected
 	}
 
-	addWordToMap(words, word)
-	word = removeWhitespace(word)
-	wordLines := strings.Split(word, "\n")
+	debugPrint("%s returned %d results for '%s'\n", categoryLabels[platform][category], len(results), query)
+	if streamed {
+		return printed
+	}
 
-	for _, w := range wordLines {
-		addWordToMap(words, w)
+	results = resolveUsers(ctx, s, filterResults(query, results))
+	matched = results
+	if flags.sortFlag != "" {
+		// -sort orders every result of the run together, so they are
+		// printed by printSorted once all searches have finished.
+		holdForSort(results)
+		return len(results)
 	}
+	return printResults(platform, category, query, results)
 }
 
-func addWordToMap(words map[string]struct{}, word string) {
-	if _, exists := words[word]; !exists {
-		words[word] = struct{}{}
+// holdForSort keeps results for printSorted. With -max-total the searches
+// stop once that many results are held, as they do once that many are
+// printed without -sort.
+func holdForSort(results []dorky.Result) {
+	outputMu.Lock()
+	defer outputMu.Unlock()
+	sortPending = append(sortPending, results...)
+	if flags.maxTotalFlag > 0 && len(sortPending) >= flags.maxTotalFlag {
+		stopSearches()
 	}
 }
 
-func checkScannerError(scanner *bufio.Scanner) {
-	if err := scanner.Err(); err != nil {
-		fmt.Printf("Error reading stdin: %s\n", err)
-		os.Exit(1)
+// printSorted sorts the results held back by -sort across every platform,
+// category, and word, and prints them.
+func printSorted() {
+	sortResults(sortPending, flags.sortFlag)
+
+	// printResults takes one group at a time, so consecutive results from
+	// the same search are printed together.
+	for start := 0; start < len(sortPending); {
+		r := sortPending[start]
+		end := start + 1
+		for end < len(sortPending) && sortPending[end].Platform == r.Platform && sortPending[end].Instance == r.Instance && sortPending[end].Category == r.Category && sortPending[end].Query == r.Query {
+			end++
+		}
+		printResults(r.Platform, r.Category, r.Query, sortPending[start:end])
+		start = end
 	}
+	sortPending = nil
 }
 
-func searchPlatforms(words map[string]struct{}, cfg config) {
-	ghClient, ghErr := createGitHubClient()
-	glClient, glErr := createGitLabClient()
+// checkCategory looks query up directly and prints it only if it exists,
+// returning how many results were printed.
+func checkCategory(ctx context.Context, s *dorky.Searcher, platform, category, query string) int {
+	results, err := cachedSearch("check", platform, s.Instance, category, query, 1, func() ([]dorky.Result, error) {
+		return s.Check(ctx, platform, category, query)
+	})
+	if err != nil && ctx.Err() != nil {
+		return 0
+	}
+	if err != nil {
+		printSearchError(platform, category, query, err)
+		return 0
+	}
 
-	if ghErr != nil {
-		fmt.Printf("Error creating GitHub client: %s\n", ghErr)
+	if results = filterPattern(results); len(results) > 0 {
+		printed := printResults(platform, category, query, results)
+		expandOrgs(ctx, s, platform, category, results)
+		return printed
 	}
+	return 0
+}
+
+// resolvePlatforms are the platforms whose users -resolve can look up.
+var resolvePlatforms = map[string]bool{
+	platformGitHub: true,
+}
 
-	if glErr != nil {
-		fmt.Printf("Error creating GitLab client: %s\n", glErr)
+// resolveUsers returns results with each user replaced by their full profile
+// when -resolve is set. Profiles are kept for the run, so a user matched by
+// several words is looked up once. A failed lookup is reported and the user
+// is kept as found.
+func resolveUsers(ctx context.Context, s *dorky.Searcher, results []dorky.Result) []dorky.Result {
+	if !flags.resolveFlag {
+		return results
 	}
 
-	for word := range words {
-		if !cfg.glOnlyFlag && ghErr == nil {
-			verbosePrint("Searching GitHub for word: %s\n", word)
-			searchGitHub(ghClient, word, cfg)
+	// results may share an array with the search's own results, which are
+	// cached as found.
+	resolved := make([]dorky.Result, len(results))
+	copy(resolved, results)
+	for i, r := range resolved {
+		if r.Category != categoryUser || !resolvePlatforms[r.Platform] || ctx.Err() != nil {
+			continue
 		}
 
-		if !cfg.ghOnlyFlag && glErr == nil {
-			verbosePrint("Searching GitLab for word: %s\n", word)
-			searchGitLab(glClient, word, cfg)
+		key := r.Platform + "\x00" + strings.ToLower(r.Name)
+		outputMu.Lock()
+		profile, ok := resolvedUsers[key]
+		outputMu.Unlock()
+		if !ok {
+			verbosePrint("Resolving %s user %s\n", platformNames[r.Platform], r.Name)
+			var err error
+			if profile, err = s.ResolveUser(ctx, r); err != nil {
+				if ctx.Err() == nil {
+					printSearchError(r.Platform, categoryUser, r.Name, err)
+				}
+				continue
+			}
+			outputMu.Lock()
+			resolvedUsers[key] = profile
+			outputMu.Unlock()
 		}
-	}
-}
 
-func cleanWord(word string) string {
-	match := urlRegexp.FindStringSubmatch(word)
-	if len(match) > 1 {
-		return match[1]
+		profile.Query, profile.Score = r.Query, r.Score
+		resolved[i] = profile
 	}
-	return word
+	return resolved
 }
 
-func removeWhitespace(word string) string {
-	removedSpaces := spaceRegexp.ReplaceAllString(word, "")
-	withHyphens := spaceRegexp.ReplaceAllString(word, "-")
-	return removedSpaces + "\n" + withHyphens
+// expandPlatforms are the platforms whose organizations -expand-orgs can
+// list repositories for.
+var expandPlatforms = map[string]bool{
+	platformGitHub: true,
+	platformGitLab: true,
 }
 
-func searchGitHub(client *github.Client, query string, cfg config) {
-	if client == nil {
+// expandOrgs lists the repositories of each organization in results when
+// -expand-orgs is set, and prints them as repository results whose query is
+// the organization. Each organization is listed once per run, however many
+// words match it.
+func expandOrgs(ctx context.Context, s *dorky.Searcher, platform, category string, results []dorky.Result) {
+	if !flags.expandOrgsFlag || category != categoryOrg || !expandPlatforms[platform] {
 		return
 	}
 
-	if cfg.orgFlag {
-		searchGitHubOrganizations(client, query, cfg.maxFlag)
+	for _, org := range results {
+		if ctx.Err() != nil {
+			return
+		}
+
+		outputMu.Lock()
+		key := platform + "\x00" + org.Instance + "\x00" + org.Name
+		done := expandedOrgs[key]
+		expandedOrgs[key] = true
+		outputMu.Unlock()
+		if done {
+			continue
+		}
+
+		verbosePrint("Listing repositories of %s organization %s\n", platformNames[platform], org.Name)
+		maxResults := categoryMax(flags, categoryRepo)
+		repos, err := cachedSearch("expand", platform, s.Instance, categoryRepo, org.Name, maxResults, func() ([]dorky.Result, error) {
+			return s.ListOrgRepositories(ctx, platform, org.Name, maxResults)
+		})
+		if err != nil && ctx.Err() != nil {
+			return
+		}
+		if err != nil {
+			printSearchError(platform, categoryRepo, org.Name, err)
+			continue
+		}
+
+		// The repositories were not matched by name, so only the filters
+		// that do not compare against the query apply.
+		repos = filterStars(filterActivity(filterLanguage(filterRepos(filterPattern(repos)))))
+		if flags.sortFlag != "" {
+			holdForSort(repos)
+			continue
+		}
+		printResults(platform, categoryRepo, org.Name, repos)
 	}
+}
 
-	if cfg.repoFlag {
-		searchGitHubRepositories(client, query, cfg.maxFlag)
+func selectedCategories(cfg config) []string {
+	set := categorySet(cfg)
+	var categories []string
+	for _, category := range allCategories {
+		if set[category] {
+			categories = append(categories, category)
+		}
 	}
+	return categories
+}
 
-	if cfg.userFlag {
-		searchGitHubUsers(client, query, cfg.maxFlag)
+// scopeFlagName returns the name of the flag restricting repository searches
+// to one account, or "" if neither -org nor -user was given.
+func scopeFlagName(cfg config) string {
+	switch {
+	case cfg.orgScope != "":
+		return "-org"
+	case cfg.userScope != "":
+		return "-user"
 	}
+	return ""
 }
 
-func searchGitLab(client *gitlab.Client, query string, cfg config) {
-	if client == nil {
-		return
+// categorySet returns the categories named with -categories, together with
+// any selected by the individual search flags such as -o and -r.
+func categorySet(cfg config) map[string]bool {
+	set := make(map[string]bool)
+	for _, category := range strings.Split(cfg.categoriesFlag, ",") {
+		if category = strings.ToLower(strings.TrimSpace(category)); category != "" {
+			set[category] = true
+		}
+	}
+
+	flagged := map[string]bool{
+		categoryOrg:     cfg.orgFlag,
+		categoryRepo:    cfg.repoFlag,
+		categoryUser:    cfg.userFlag,
+		categoryCode:    cfg.codeFlag,
+		categoryGist:    cfg.gistFlag,
+		categoryTopic:   cfg.topicsFlag,
+		categorySnippet: cfg.snippetFlag,
+	}
+	for category, on := range flagged {
+		if on {
+			set[category] = true
+		}
 	}
+	return set
+}
 
-	if cfg.orgFlag || cfg.userFlag {
-		searchGitLabGroupsAndUsers(client, query, cfg.maxFlag)
+// selectedPlatforms returns the platforms named with -platforms, together with
+// any selected by the older per-platform flags such as -gh and -gl. An empty
+// set means no platform was singled out.
+func selectedPlatforms(cfg config) map[string]bool {
+	selected := make(map[string]bool)
+	for _, platform := range strings.Split(cfg.platformsFlag, ",") {
+		if platform = strings.ToLower(strings.TrimSpace(platform)); platform != "" {
+			selected[platform] = true
+		}
 	}
 
-	if cfg.repoFlag {
-		searchGitLabProjects(client, query, cfg.maxFlag)
+	legacy := map[string]bool{
+		platformGitHub:    cfg.ghOnlyFlag,
+		platformGitLab:    cfg.glOnlyFlag,
+		platformBitbucket: cfg.bbOnlyFlag,
+		platformGitea:     cfg.giteaFlag,
+		platformAzure:     cfg.azFlag,
+		platformSourceHut: cfg.srhtFlag,
+	}
+	for platform, on := range legacy {
+		if on {
+			selected[platform] = true
+		}
 	}
+	return selected
 }
 
-func searchGitHubOrganizations(client *github.Client, query string, maxResults int) {
-	ctx := context.Background()
+// platformEnabled reports whether a platform should be searched. Without any
+// platforms selected every platform is enabled; otherwise just those named.
+func platformEnabled(cfg config, platform string) bool {
+	selected := selectedPlatforms(cfg)
+	return len(selected) == 0 || selected[platform]
+}
 
-	opt := &github.SearchOptions{ListOptions: github.ListOptions{PerPage: maxResults}}
-	results, _, err := client.Search.Users(ctx, "type:org "+query, opt)
-	if err != nil {
-		fmt.Printf("Error searching organizations: %s\n", err)
-		return
+// gitHosts are platforms whose URLs name an organization in their first path
+// segment, which is a better search term than the host itself.
+var gitHosts = map[string]bool{
+	"github.com":    true,
+	"gitlab.com":    true,
+	"bitbucket.org": true,
+}
+
+// cleanWord reduces a URL, SSH git remote, or hostname to a search term: the
+// organization for git hosting URLs, otherwise the registrable domain with
+// any subdomain, port, and path removed. Other words are returned unchanged.
+func cleanWord(word string) string {
+	host, urlPath := splitLocation(word)
+	if host == "" {
+		return word
 	}
 
-	orgLogins := make([]string, len(results.Users))
-	for i, org := range results.Users {
-		orgLogins[i] = *org.Login
+	if gitHosts[host] {
+		if org := strings.SplitN(strings.Trim(urlPath, "/"), "/", 2)[0]; org != "" {
+			return strings.TrimSuffix(org, ".git")
+		}
 	}
 
-	printResults(fmt.Sprintf("GitHub organizations matching '%s'", query), orgLogins)
+	if net.ParseIP(host) != nil {
+		return host
+	}
+	if domain, err := publicsuffix.EffectiveTLDPlusOne(host); err == nil {
+		return domain
+	}
+	return host
 }
 
-func searchGitHubRepositories(client *github.Client, query string, maxResults int) {
-	ctx := context.Background()
+// splitLocation returns the lowercased host and path of word if it looks like
+// a URL, an scp-style SSH remote such as git@github.com:org/repo.git, or a
+// bare hostname.
+func splitLocation(word string) (host, urlPath string) {
+	if match := sshRegexp.FindStringSubmatch(word); match != nil && !strings.Contains(word, "://") {
+		return strings.ToLower(match[1]), match[2]
+	}
+
+	if !strings.Contains(word, "://") {
+		if !strings.Contains(word, ".") || spaceRegexp.MatchString(word) {
+			return "", ""
+		}
+		word = "//" + word
+	}
 
-	opt := &github.SearchOptions{ListOptions: github.ListOptions{PerPage: maxResults}}
-	results, _, err := client.Search.Repositories(ctx, query, opt)
+	u, err := url.Parse(word)
 	if err != nil {
-		fmt.Printf("Error searching repositories: %s\n", err)
-		return
+		return "", ""
+	}
+	return strings.ToLower(u.Hostname()), u.Path
+}
+
+// dropShortWords removes words with fewer than minLen characters, returning
+// how many were removed.
+func dropShortWords(words *wordList, minLen int) int {
+	kept := words.words[:0]
+	for _, word := range words.words {
+		if utf8.RuneCountInString(word) < minLen {
+			delete(words.set, caseKey(word))
+			continue
+		}
+		kept = append(kept, word)
+	}
+	dropped := len(words.words) - len(kept)
+	words.words = kept
+	return dropped
+}
+
+// wordVariants returns the queries generated for word: the word itself and,
+// if it contains whitespace, the forms with the whitespace removed and with
+// it replaced by hyphens. The result never holds duplicates or empty strings,
+// so a single-token word yields exactly one query.
+func wordVariants(word string) []string {
+	candidates := []string{
+		word,
+		spaceRegexp.ReplaceAllString(word, ""),
+		spaceRegexp.ReplaceAllString(word, "-"),
+	}
+
+	var variants []string
+	for _, candidate := range candidates {
+		if candidate == "" || containsString(variants, candidate) {
+			continue
+		}
+		variants = append(variants, candidate)
 	}
+	return variants
+}
 
-	repoNames := make([]string, len(results.Repositories))
-	for i, repo := range results.Repositories {
-		repoNames[i] = *repo.FullName
+func containsString(list []string, s string) bool {
+	for _, item := range list {
+		if item == s {
+			return true
+		}
 	}
+	return false
+}
 
-	printResults(fmt.Sprintf("GitHub repositories matching '%s'", query), repoNames)
+// searchError is a failed search, kept with the word, platform, and category
+// it was for.
+type searchError struct {
+	query    string
+	platform string
+	category string
+	err      error
 }
 
-func searchGitHubUsers(client *github.Client, query string, maxResults int) {
-	ctx := context.Background()
+func (e searchError) message() string {
+	if errors.Is(e.err, context.DeadlineExceeded) {
+		return "timed out"
+	}
+	return e.err.Error()
+}
 
-	opt := &github.SearchOptions{ListOptions: github.ListOptions{PerPage: maxResults}}
-	results, _, err := client.Search.Users(ctx, "type:user "+query, opt)
-	if err != nil {
-		fmt.Printf("Error searching users: %s\n", err)
+// printSearchError records a failed search for the report at the end of the
+// run. With -v it is also logged as it happens.
+func printSearchError(platform, category, query string, err error) {
+	atomic.AddInt32(&searchFailures, 1)
+
+	e := searchError{query: query, platform: platform, category: category, err: err}
+	searchErrorsMu.Lock()
+	searchErrors = append(searchErrors, e)
+	searchErrorsMu.Unlock()
+
+	verbosePrint("Error searching %s for '%s': %s\n", categoryLabels[platform][category], query, e.message())
+
+	// Start a new header for whatever is printed next, so results never
+	// look like they continue a group an error was logged in.
+	outputMu.Lock()
+	lastGroup = ""
+	outputMu.Unlock()
+}
+
+// jsonError is a failed search as reported on stderr under -json.
+type jsonError struct {
+	Error    string `json:"error"`
+	Query    string `json:"query"`
+	Platform string `json:"platform"`
+	Category string `json:"category"`
+}
+
+// printErrorReport lists every failed search together, so failures in a long
+// run are not lost among the other output.
+func printErrorReport() {
+	searchErrorsMu.Lock()
+	defer searchErrorsMu.Unlock()
+
+	if len(searchErrors) == 0 {
+		return
+	}
+
+	// Under -json each failure is its own JSON object on stderr, so the
+	// results on stdout never mix with errors.
+	if flags.jsonFlag {
+		if flags.quietFlag {
+			return
+		}
+		encoder := json.NewEncoder(os.Stderr)
+		for _, e := range searchErrors {
+			record := jsonError{Error: e.message(), Query: e.query, Platform: e.platform, Category: e.category}
+			if err := encoder.Encode(record); err != nil {
+				errorPrint("Error encoding search error: %s\n", err)
+			}
+		}
 		return
 	}
 
-	userLogins := make([]string, len(results.Users))
-	for i, user := range results.Users {
-		userLogins[i] = *user.Login
+	if len(searchErrors) == 1 {
+		errorPrint("1 search failed:\n")
+	} else {
+		errorPrint("%d searches failed:\n", len(searchErrors))
+	}
+	for _, e := range searchErrors {
+		errorPrint("%s (%s/%s): %s\n", e.query, e.platform, e.category, e.message())
+	}
+}
+
+// describedCategories are named by URL, so bullet output adds their
+// description to tell them apart. Their query is an owner rather than a name
+// to match, so -exact leaves them alone.
+var describedCategories = map[string]bool{
+	categoryGist:    true,
+	categorySnippet: true,
+}
+
+// comparesName reports whether r matched its query by name, so -exact and
+// -min-score apply to it. Described categories match an owner, and GitLab
+// topic results are projects tagged with the query.
+func comparesName(r dorky.Result) bool {
+	if describedCategories[r.Category] {
+		return false
 	}
+	return r.Platform != platformGitLab || r.Category != categoryTopic
+}
 
-	printResults(fmt.Sprintf("GitHub users matching '%s'", query), userLogins)
+// filterResults scores results against query and applies -exact, -min-score,
+// -filter, -exclude, -lang, -star-min, and the fork, archive, and activity
+// filters.
+func filterResults(query string, results []dorky.Result) []dorky.Result {
+	return filterStars(filterActivity(filterLanguage(filterRepos(filterPattern(filterScore(query, filterExact(query, results)))))))
 }
 
-func createGitHubClient() (*github.Client, error) {
-	ctx := context.Background()
-	token := os.Getenv("GITHUB_ACCESS_TOKEN")
-	if token == "" {
-		return nil, errors.New("GITHUB_ACCESS_TOKEN environment variable is not set")
+// starPlatforms are the platforms whose repository results carry a star
+// count. -star-min leaves repositories from the others unfiltered.
+var starPlatforms = map[string]bool{
+	platformGitHub: true,
+	platformGitLab: true,
+	platformGitea:  true,
+}
+
+// filterStars keeps only repositories with at least -star-min stars.
+func filterStars(results []dorky.Result) []dorky.Result {
+	if flags.starMinFlag <= 0 {
+		return results
 	}
 
-	ts := oauth2.StaticTokenSource(
-		&oauth2.Token{AccessToken: token},
-	)
-	tc := oauth2.NewClient(ctx, ts)
-	tc.Transport = &rateLimitedTransport{
-		transport: tc.Transport,
-		limiter:   rate.NewLimiter(rate.Every(10), 10),
+	var kept []dorky.Result
+	for _, r := range results {
+		if r.Category == categoryRepo && starPlatforms[r.Platform] && r.Stars < flags.starMinFlag {
+			continue
+		}
+		kept = append(kept, r)
 	}
+	return kept
+}
 
-	client := github.NewClient(tc)
+// dateLayout is the format of -active-since and -stale-before.
+const dateLayout = "2006-01-02"
 
-	return client, nil
+// filterActivity applies -active-since and -stale-before to repositories.
+// Repositories without a last activity time are kept.
+func filterActivity(results []dorky.Result) []dorky.Result {
+	if activeAfter.IsZero() && activeBefore.IsZero() {
+		return results
+	}
+
+	var kept []dorky.Result
+	for _, r := range results {
+		if r.Category == categoryRepo && !r.LastActive.IsZero() {
+			if !activeAfter.IsZero() && r.LastActive.Before(activeAfter) {
+				continue
+			}
+			if !activeBefore.IsZero() && !r.LastActive.Before(activeBefore) {
+				continue
+			}
+		}
+		kept = append(kept, r)
+	}
+	return kept
 }
 
-type rateLimitedTransport struct {
-	transport http.RoundTripper
-	limiter   *rate.Limiter
+// languagePlatforms are the platforms whose repository results carry a
+// language. -lang leaves repositories from the others unfiltered, since
+// finding their language would take an extra request per repository.
+var languagePlatforms = map[string]bool{
+	platformGitHub:    true,
+	platformBitbucket: true,
 }
 
-func (t *rateLimitedTransport) RoundTrip(req *http.Request) (*http.Response, error) {
-	if err := t.limiter.Wait(context.Background()); err != nil {
-		return nil, err
+// filterLanguage keeps only repositories whose primary language was given
+// to -lang.
+func filterLanguage(results []dorky.Result) []dorky.Result {
+	if languages == nil {
+		return results
 	}
 
-	return t.transport.RoundTrip(req)
+	var kept []dorky.Result
+	for _, r := range results {
+		if r.Category == categoryRepo && languagePlatforms[r.Platform] && !languages[strings.ToLower(r.Language)] {
+			continue
+		}
+		kept = append(kept, r)
+	}
+	return kept
 }
 
-func searchGitLabGroupsAndUsers(client *gitlab.Client, query string, maxResults int) {
-	opt := &gitlab.ListGroupsOptions{Search: gitlab.String(query), ListOptions: gitlab.ListOptions{PerPage: maxResults}}
-	groups, _, err := client.Groups.ListGroups(opt)
-	if err != nil {
-		fmt.Printf("Error searching GitLab groups: %s\n", err)
-		return
+// filterRepos drops forked repositories unless -include-forks was given and
+// archived ones unless -archived was given.
+func filterRepos(results []dorky.Result) []dorky.Result {
+	if flags.includeForks && flags.archivedFlag {
+		return results
 	}
 
-	if flags.orgFlag {
-		groupFullPaths := make([]string, len(groups))
-		for i, group := range groups {
-			groupFullPaths[i] = group.FullPath
+	var kept []dorky.Result
+	for _, r := range results {
+		if (r.Fork && !flags.includeForks) || (r.Archived && !flags.archivedFlag) {
+			continue
 		}
+		kept = append(kept, r)
+	}
+	return kept
+}
 
-		printResults(fmt.Sprintf("GitLab groups matching '%s'", query), groupFullPaths)
+// filterScore sets each result's similarity to query, comparing the last
+// path segment of its name as -exact does, and drops those below -min-score.
+func filterScore(query string, results []dorky.Result) []dorky.Result {
+	var kept []dorky.Result
+	for _, r := range results {
+		r.Score = dorky.Similarity(normalizeUnicode(path.Base(r.Name)), normalizeUnicode(query))
+		if !comparesName(r) || r.Score >= flags.minScoreFlag {
+			kept = append(kept, r)
+		}
 	}
+	return kept
+}
 
-	users, _, err := client.Users.ListUsers(&gitlab.ListUsersOptions{Search: gitlab.String(query), ListOptions: gitlab.ListOptions{PerPage: maxResults}})
-	if err != nil {
-		fmt.Printf("Error searching GitLab users: %s\n", err)
-		return
+// filterExact keeps only results whose last path segment equals the query,
+// so repositories are compared without their owner and GitLab groups by
+// their leaf rather than full path.
+func filterExact(query string, results []dorky.Result) []dorky.Result {
+	if !flags.exactFlag {
+		return results
 	}
 
-	if flags.userFlag {
-		userUsernames := make([]string, len(users))
-		for i, user := range users {
-			userUsernames[i] = user.Username
+	var matches []dorky.Result
+	for _, r := range results {
+		if !comparesName(r) || caseKey(path.Base(r.Name)) == caseKey(query) {
+			matches = append(matches, r)
 		}
+	}
+	return matches
+}
+
+const (
+	sortStars   = "stars"
+	sortName    = "name"
+	sortUpdated = "updated"
+	sortScore   = "score"
+)
 
-		printResults(fmt.Sprintf("GitLab users matching '%s'", query), userUsernames)
+// sortResults orders results in place, most starred, most recently active, or
+// closest to the query first. Results without a last activity time, such as
+// most users and organizations, sort last by updated. Ties and an empty key
+// keep the order the API returned.
+func sortResults(results []dorky.Result, key string) {
+	switch key {
+	case sortStars:
+		sort.SliceStable(results, func(i, j int) bool {
+			return results[i].Stars > results[j].Stars
+		})
+	case sortName:
+		sort.SliceStable(results, func(i, j int) bool {
+			return strings.ToLower(results[i].Name) < strings.ToLower(results[j].Name)
+		})
+	case sortUpdated:
+		sort.SliceStable(results, func(i, j int) bool {
+			return results[i].LastActive.After(results[j].LastActive)
+		})
+	case sortScore:
+		sort.SliceStable(results, func(i, j int) bool {
+			return results[i].Score > results[j].Score
+		})
 	}
 }
 
-func searchGitLabProjects(client *gitlab.Client, query string, maxResults int) {
-	opt := &gitlab.ListProjectsOptions{Search: gitlab.String(query), ListOptions: gitlab.ListOptions{PerPage: maxResults}}
-	projects, _, err := client.Projects.ListProjects(opt)
+// parseFormat compiles a -format template, adding a trailing newline if it
+// has none. The template is tried on an empty Result so unknown fields are
+// reported at startup rather than on the first match.
+func parseFormat(format string) (*template.Template, error) {
+	if !strings.HasSuffix(format, "\n") {
+		format += "\n"
+	}
+
+	tmpl, err := template.New("format").Parse(format)
 	if err != nil {
-		fmt.Printf("Error searching GitLab projects: %s\n", err)
-		return
+		return nil, err
+	}
+	if err := tmpl.Execute(io.Discard, dorky.Result{}); err != nil {
+		return nil, err
 	}
+	return tmpl, nil
+}
 
-	projectFullPaths := make([]string, len(projects))
-	for i, project := range projects {
-		projectFullPaths[i] = project.PathWithNamespace
+// display returns the text shown for r in the bullet and simple formats.
+func display(r dorky.Result) string {
+	if flags.urlsFlag && r.URL != "" {
+		return r.URL
 	}
+	return r.Name
+}
 
-	printResults(fmt.Sprintf("GitLab projects matching '%s'", query), projectFullPaths)
+// plainNames returns a copy of results with each name cut to its last path
+// segment, so acme/api becomes api and acme/platform/infra becomes infra.
+func plainNames(results []dorky.Result) []dorky.Result {
+	plain := make([]dorky.Result, len(results))
+	for i, r := range results {
+		r.Name = path.Base(r.Name)
+		plain[i] = r
+	}
+	return plain
 }
 
-func createGitLabClient() (*gitlab.Client, error) {
-	token := os.Getenv("GITLAB_ACCESS_TOKEN")
-	if token == "" {
-		return nil, errors.New("GITLAB_ACCESS_TOKEN environment variable is not set")
+// profileLines describes a resolved user's profile for the default output,
+// one field per line. It returns nil for results without a profile.
+func profileLines(r dorky.Result) []string {
+	if !flags.resolveFlag || r.Category != categoryUser {
+		return nil
 	}
 
-	client, err := gitlab.NewClient(token)
-	if err != nil {
-		return nil, err
+	var lines []string
+	for _, field := range []struct{ name, value string }{
+		{"name", r.DisplayName},
+		{"company", r.Company},
+		{"blog", r.Blog},
+		{"email", r.Email},
+	} {
+		if field.value != "" {
+			lines = append(lines, field.name+": "+field.value)
+		}
+	}
+	if r.PublicRepos > 0 {
+		lines = append(lines, fmt.Sprintf("public repositories: %d", r.PublicRepos))
+	}
+	return lines
+}
+
+// filterPattern applies -filter, -exclude, and -contains to each result's
+// name.
+func filterPattern(results []dorky.Result) []dorky.Result {
+	if filterRegexp == nil && excludeRegexp == nil && len(flags.containsFlag) == 0 {
+		return results
+	}
+
+	var matches []dorky.Result
+	for _, r := range results {
+		if filterRegexp != nil && !filterRegexp.MatchString(r.Name) {
+			continue
+		}
+		if len(flags.containsFlag) > 0 && !containsAny(r.Name, flags.containsFlag) {
+			continue
+		}
+		if excludeRegexp != nil && excludeRegexp.MatchString(r.Name) {
+			continue
+		}
+		matches = append(matches, r)
 	}
+	return matches
+}
+
+// containsAny reports whether name contains any of substrings, ignoring
+// case.
+func containsAny(name string, substrings []string) bool {
+	name = strings.ToLower(name)
+	for _, s := range substrings {
+		if strings.Contains(name, strings.ToLower(s)) {
+			return true
+		}
+	}
+	return false
+}
+
+type jsonRecord struct {
+	Platform    string     `json:"platform"`
+	Category    string     `json:"category"`
+	Query       string     `json:"query"`
+	Name        string     `json:"name"`
+	URL         string     `json:"url,omitempty"`
+	Description string     `json:"description,omitempty"`
+	Stars       int        `json:"stars,omitempty"`
+	Fork        bool       `json:"fork,omitempty"`
+	Archived    bool       `json:"archived,omitempty"`
+	Language    string     `json:"language,omitempty"`
+	DisplayName string     `json:"display_name,omitempty"`
+	Company     string     `json:"company,omitempty"`
+	Blog        string     `json:"blog,omitempty"`
+	Email       string     `json:"email,omitempty"`
+	PublicRepos int        `json:"public_repos,omitempty"`
+	Instance    string     `json:"instance,omitempty"`
+	LastActive  *time.Time `json:"last_active,omitempty"`
+	Score       float64    `json:"score,omitempty"`
+
+	// GuessedEmails are the unverified addresses -emails derived from the
+	// user's name.
+	GuessedEmails []string `json:"guessed_emails,omitempty"`
+
+	// Meta holds the extra fields of the input record the query came from,
+	// when reading -input json.
+	Meta map[string]interface{} `json:"meta,omitempty"`
+}
 
-	return client, nil
+func newJSONRecord(r dorky.Result) jsonRecord {
+	record := jsonRecord{
+		Platform:    r.Platform,
+		Category:    r.Category,
+		Query:       r.Query,
+		Name:        r.Name,
+		Description: r.Description,
+		Stars:       r.Stars,
+		Fork:        r.Fork,
+		Archived:    r.Archived,
+		Language:    r.Language,
+		DisplayName: r.DisplayName,
+		Company:     r.Company,
+		Blog:        r.Blog,
+		Email:       r.Email,
+		PublicRepos: r.PublicRepos,
+		Instance:    r.Instance,
+		Score:       math.Round(r.Score*1000) / 1000,
+		Meta:        wordMeta[r.Query],
+	}
+	record.GuessedEmails = guessEmails(r)
+	if flags.urlsFlag {
+		record.URL = r.URL
+	}
+	if !r.LastActive.IsZero() {
+		record.LastActive = &r.LastActive
+	}
+	return record
 }
 
-func printResults(header string, results []string) {
-	if flags.simpleFlag {
-		for _, result := range results {
-			fmt.Println(result)
+// printResults prints results for query and returns how many were printed
+// after duplicates were removed.
+func printResults(platform, category, query string, results []dorky.Result) int {
+	outputMu.Lock()
+	defer outputMu.Unlock()
+
+	// Results reported by earlier runs go first, so -first picks from the
+	// rest.
+	results = removeSeenBefore(results)
+	if flags.firstFlag {
+		results = firstUnseen(platform, category, results)
+	} else if !flags.dupesFlag {
+		results = removeSeen(platform, category, results)
+	}
+	if flags.maxTotalFlag > 0 {
+		// Searches already running when the limit was reached print
+		// nothing, not even their header.
+		remaining := flags.maxTotalFlag - resultCount
+		if remaining <= 0 {
+			return 0
+		}
+		if len(results) > remaining {
+			results = results[:remaining]
+		}
+	}
+	recordSeen(results)
+	if flags.webhookFlag != "" {
+		webhookResults = append(webhookResults, results...)
+	}
+	resultCount += len(results)
+	tallySummary(platform, category, query, len(results))
+	if flags.maxTotalFlag > 0 && resultCount >= flags.maxTotalFlag {
+		stopSearches()
+	}
+
+	if flags.countFlag {
+		counts[platform+"\x00"+category] += len(results)
+		return len(results)
+	}
+
+	if flags.plainNamesFlag {
+		results = plainNames(results)
+	}
+
+	w := resultOutput(platform, category)
+	if flags.jsonFlag {
+		encoder := json.NewEncoder(w)
+		for _, r := range results {
+			if err := encoder.Encode(newJSONRecord(r)); err != nil {
+				errorPrint("Error encoding result: %s\n", err)
+			}
+		}
+	} else if formatTmpl != nil {
+		for _, r := range results {
+			if err := formatTmpl.Execute(w, r); err != nil {
+				errorPrint("Error formatting result: %s\n", err)
+			}
+		}
+	} else if flags.csvFlag {
+		writeCSV(w, results)
+	} else if outputFields != nil {
+		for _, r := range results {
+			fmt.Fprintln(w, strings.Join(fieldValues(r), "\t"))
+		}
+	} else if flags.simpleFlag || flags.quietFlag {
+		for _, r := range results {
+			fmt.Fprintln(w, display(r))
 		}
 	} else {
-		fmt.Printf("\n%s:\n", header)
-		for _, result := range results {
-			fmt.Printf("- %s\n", result)
+		// Pages of one search arrive separately and may interleave with other
+		// searches, so the header is repeated only when the group changes.
+		var instance string
+		if len(results) > 0 {
+			instance = results[0].Instance
+		}
+		if group := platform + "\x00" + instance + "\x00" + category + "\x00" + query; group != lastGroup {
+			label := categoryLabels[platform][category]
+			if instance != "" {
+				label += " on " + instance
+			}
+			header := fmt.Sprintf("%s matching '%s':", label, query)
+			fmt.Fprintf(w, "\n%s\n", colorize(colorHeader, header))
+			lastGroup = group
+		}
+		for _, r := range results {
+			text := display(r)
+			if strings.EqualFold(path.Base(r.Name), query) {
+				text = colorize(colorExact, text)
+			}
+			if describedCategories[category] && r.Description != "" {
+				fmt.Fprintf(w, "- %s (%s)\n", text, r.Description)
+			} else {
+				fmt.Fprintf(w, "- %s\n", text)
+			}
+			for _, line := range profileLines(r) {
+				fmt.Fprintf(w, "    %s\n", line)
+			}
+			for _, email := range guessEmails(r) {
+				fmt.Fprintf(w, "    guessed email: %s\n", email)
+			}
+		}
+	}
+	return len(results)
+}
+
+// printDryRun prints the query each enabled platform would receive for every
+// word in search order, grouped by platform and category. Check mode looks
+// words up as given.
+func printDryRun(words *wordList, cfg config) {
+	// Searching both GitHub organizations and users shares one untyped
+	// query, as in createSearcher, falling back to the typed query.
+	categories := categorySet(cfg)
+	combined := categories[categoryOrg] && categories[categoryUser]
+
+	for _, platform := range allPlatforms {
+		if !platformEnabled(cfg, platform) {
+			continue
+		}
+
+		for _, category := range selectedCategories(cfg) {
+			if !dorky.Supported(platform, category) {
+				continue
+			}
+
+			fmt.Fprintf(output, "\n%s:\n", categoryLabels[platform][category])
+			for _, word := range words.words {
+				if cfg.checkFlag {
+					fmt.Fprintf(output, "- %s\n", word)
+				} else if platform == platformGitHub && combined && (category == categoryOrg || category == categoryUser) {
+					// The typed query is only sent when accounts of the
+					// other type crowd this category out.
+					fmt.Fprintf(output, "- %s, then %s if it finds too few\n", word, dorky.SearchQuery(platform, category, word))
+				} else if platform == platformGitHub && category == categoryRepo {
+					fmt.Fprintf(output, "- %s\n", dorky.RepoQuery(cfg.orgScope, cfg.userScope, word, cfg.starMinFlag))
+				} else {
+					fmt.Fprintf(output, "- %s\n", dorky.SearchQuery(platform, category, word))
+				}
+			}
+		}
+	}
+}
+
+// printCounts prints the number of matches found for every platform and
+// category searched, then the total. With -q only the total is printed.
+func printCounts(s *dorky.Searcher, cfg config) {
+	outputMu.Lock()
+	defer outputMu.Unlock()
+
+	if cfg.quietFlag {
+		fmt.Fprintln(output, resultCount)
+		return
+	}
+
+	for _, platform := range searchedPlatforms(s) {
+		for _, category := range selectedCategories(cfg) {
+			if dorky.Supported(platform, category) {
+				fmt.Fprintf(output, "%s: %d\n", categoryLabels[platform][category], counts[platform+"\x00"+category])
+			}
+		}
+	}
+	fmt.Fprintf(output, "Total: %d\n", resultCount)
+}
+
+// firstUnseen returns the first result not printed earlier in the run, for
+// -first. Callers must hold outputMu.
+func firstUnseen(platform, category string, results []dorky.Result) []dorky.Result {
+	for _, r := range results {
+		if flags.dupesFlag || len(removeSeen(platform, category, []dorky.Result{r})) > 0 {
+			return []dorky.Result{r}
+		}
+	}
+	return nil
+}
+
+// removeSeen drops results already printed earlier in the run. Callers must
+// hold outputMu.
+func removeSeen(platform, category string, results []dorky.Result) []dorky.Result {
+	var unseen []dorky.Result
+	for _, r := range results {
+		key := platform + "\x00" + r.Instance + "\x00" + category + "\x00" + caseKey(r.Name)
+		if _, exists := seen[key]; exists {
+			continue
 		}
+		seen[key] = struct{}{}
+		unseen = append(unseen, r)
 	}
+	return unseen
 }
@@ -0,0 +1,273 @@
+package main
+
+import (
+	"bytes"
+	"context"
+	"fmt"
+	"net/http"
+	"net/http/httptest"
+	"strings"
+	"testing"
+	"time"
+
+	"github.com/codingo/dorky/pkg/dorky"
+)
+
+// setupRun sets flags to cfg and resets the state of a run, returning the
+// buffer results are printed to. Everything is restored when t ends.
+func setupRun(t *testing.T, cfg config) *bytes.Buffer {
+	t.Helper()
+	savedFlags, savedOutput := flags, output
+	t.Cleanup(func() {
+		flags, output = savedFlags, savedOutput
+	})
+
+	var buf bytes.Buffer
+	flags, output = cfg, &buf
+	seen = make(map[string]struct{})
+	lastGroup = ""
+	resultCount = 0
+	searchFailures = 0
+	searchErrors = nil
+	return &buf
+}
+
+func TestScanWords(t *testing.T) {
+	// A list of words on one line, longer than bufio.Scanner's 64KB default.
+	var list []string
+	for i := 0; i < 20000; i++ {
+		list = append(list, fmt.Sprintf("word%d", i))
+	}
+	pasted := strings.Join(list, " ")
+	unbroken := strings.Repeat("x", maxLineLength+10)
+
+	tests := []struct {
+		name  string
+		input string
+		want  []string
+	}{
+		{"one word per line", "acme\nglobex\n", []string{"acme", "globex"}},
+		{"short line kept whole", "acme corp\n", []string{"acme corp"}},
+		{"comments skipped", "# targets\nacme\n", []string{"acme"}},
+		{"long line split on whitespace", "acme\n" + pasted + "\nglobex\n", append(append([]string{"acme"}, list...), "globex")},
+		{"long line without whitespace", unbroken + "\n", []string{unbroken[:maxLineLength], "xxxxxxxxxx"}},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			got := scanWords(context.Background(), strings.NewReader(tt.input), newWordList(), config{})
+			if len(got) != len(tt.want) {
+				t.Fatalf("got %d words, want %d", len(got), len(tt.want))
+			}
+			for i := range tt.want {
+				if got[i] != tt.want[i] {
+					t.Errorf("word %d = %.20q, want %.20q", i, got[i], tt.want[i])
+				}
+			}
+		})
+	}
+}
+
+func TestScanWordsCancelled(t *testing.T) {
+	ctx, cancel := context.WithCancel(context.Background())
+	cancel()
+
+	for _, input := range []string{inputLines, inputJSON, inputJSONArray} {
+		text := "acme\nglobex\n"
+		switch input {
+		case inputJSON:
+			text = `{"word": "acme"}` + "\n"
+		case inputJSONArray:
+			text = `["acme", "globex"]`
+		}
+		if got := scanWords(ctx, strings.NewReader(text), newWordList(), config{inputFlag: input}); len(got) != 0 {
+			t.Errorf("-input %s read %q after cancellation, want nothing", input, got)
+		}
+	}
+}
+
+func TestSearchPlatformsContinuesAfterError(t *testing.T) {
+	server := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		switch r.URL.Path {
+		case "/api/v4/projects":
+			w.WriteHeader(http.StatusInternalServerError)
+			fmt.Fprint(w, `{"message": "500 Internal Server Error"}`)
+		case "/api/v4/users":
+			fmt.Fprint(w, `[{"username": "acme-dev", "web_url": "https://gitlab.example.com/acme-dev"}]`)
+		}
+	}))
+	defer server.Close()
+
+	client, err := dorky.NewGitLabClient("token", server.URL, dorky.ClientOptions{})
+	if err != nil {
+		t.Fatal(err)
+	}
+	cfg := config{repoFlag: true, userFlag: true, simpleFlag: true, threadsFlag: 1, maxFlag: 10}
+	buf := setupRun(t, cfg)
+
+	words := newWordList()
+	addWord(words, "acme")
+	searchPlatforms(context.Background(), &dorky.Searcher{GitLab: client}, words, cfg)
+
+	if got := buf.String(); got != "acme-dev\n" {
+		t.Errorf("output = %q, want the user found after the repository search failed", got)
+	}
+	if searchFailures != 1 || len(searchErrors) != 1 || searchErrors[0].category != categoryRepo {
+		t.Errorf("failures = %d, errors = %+v, want one failed repository search", searchFailures, searchErrors)
+	}
+}
+
+func TestReadAndCleanWordsOrder(t *testing.T) {
+	args := []string{"acme corp", "globex", "Acme Corp", "initech labs", "globex"}
+	want := []string{"acme corp", "acmecorp", "acme-corp", "globex", "initech labs", "initechlabs", "initech-labs"}
+
+	// Map iteration order changes between runs, so a few runs would catch
+	// order taken from the map rather than the input.
+	for run := 0; run < 5; run++ {
+		got := readAndCleanWords(context.Background(), config{}, args).words
+		if strings.Join(got, "|") != strings.Join(want, "|") {
+			t.Fatalf("run %d: words = %q, want %q", run, got, want)
+		}
+	}
+}
+
+// resultNames returns the names of results.
+func resultNames(results []dorky.Result) []string {
+	var names []string
+	for _, r := range results {
+		names = append(names, r.Name)
+	}
+	return names
+}
+
+func TestMixedCase(t *testing.T) {
+	results := []dorky.Result{
+		{Platform: dorky.PlatformGitHub, Category: categoryRepo, Name: "acme/api"},
+		{Platform: dorky.PlatformGitHub, Category: categoryRepo, Name: "Acme/API"},
+		{Platform: dorky.PlatformGitHub, Category: categoryRepo, Name: "acme/apis"},
+	}
+
+	tests := []struct {
+		caseSensitive bool
+		words         []string
+		unseen        []string
+		exact         []string
+	}{
+		{false, []string{"Acme"}, []string{"acme/api", "acme/apis"}, []string{"acme/api", "Acme/API"}},
+		{true, []string{"Acme", "acme", "ACME"}, []string{"acme/api", "Acme/API", "acme/apis"}, []string{"acme/api"}},
+	}
+	for _, tt := range tests {
+		t.Run(fmt.Sprintf("case-sensitive=%v", tt.caseSensitive), func(t *testing.T) {
+			setupRun(t, config{caseSensitiveFlag: tt.caseSensitive, exactFlag: true})
+
+			words := newWordList()
+			for _, word := range []string{"Acme", "acme", "ACME"} {
+				addWord(words, word)
+			}
+			if strings.Join(words.words, "|") != strings.Join(tt.words, "|") {
+				t.Errorf("words = %q, want %q", words.words, tt.words)
+			}
+
+			unseen := resultNames(removeSeen(dorky.PlatformGitHub, categoryRepo, results))
+			if strings.Join(unseen, "|") != strings.Join(tt.unseen, "|") {
+				t.Errorf("removeSeen kept %q, want %q", unseen, tt.unseen)
+			}
+
+			exact := resultNames(filterExact("api", results))
+			if strings.Join(exact, "|") != strings.Join(tt.exact, "|") {
+				t.Errorf("filterExact kept %q, want %q", exact, tt.exact)
+			}
+		})
+	}
+}
+
+func TestCleanWord(t *testing.T) {
+	tests := []struct {
+		in, want string
+	}{
+		{"acme", "acme"},
+		{"acme corp", "acme corp"},
+		{"https://github.com/acme/api", "acme"},
+		{"https://gitlab.com/acme/platform/api.git", "acme"},
+		{"https://bitbucket.org/acme", "acme"},
+		{"git@github.com:acme/api.git", "acme"},
+		{"git@gitlab.com:acme.git", "acme"},
+		{"ssh://git@github.com/acme/api.git", "acme"},
+		{"https://www.acme.com/about", "acme.com"},
+		{"https://api.acme.co.uk:8443/v1", "acme.co.uk"},
+		{"acme.com:8080", "acme.com"},
+		{"dev.acme.io", "acme.io"},
+		{"http://10.0.0.1:8080/", "10.0.0.1"},
+		{"HTTPS://WWW.ACME.COM", "acme.com"},
+	}
+	for _, tt := range tests {
+		if got := cleanWord(tt.in); got != tt.want {
+			t.Errorf("cleanWord(%q) = %q, want %q", tt.in, got, tt.want)
+		}
+	}
+}
+
+func TestWordVariants(t *testing.T) {
+	tests := []struct {
+		in   string
+		want []string
+	}{
+		{"acme", []string{"acme"}},
+		{"acme corp", []string{"acme corp", "acmecorp", "acme-corp"}},
+		{"acme  corp\tlabs", []string{"acme  corp\tlabs", "acmecorplabs", "acme-corp-labs"}},
+		{"acme-corp", []string{"acme-corp"}},
+		{"", nil},
+	}
+	for _, tt := range tests {
+		got := wordVariants(tt.in)
+		if strings.Join(got, "|") != strings.Join(tt.want, "|") || len(got) != len(tt.want) {
+			t.Errorf("wordVariants(%q) = %q, want %q", tt.in, got, tt.want)
+		}
+	}
+}
+
+func TestProcessWordSingleToken(t *testing.T) {
+	words := newWordList()
+	processWord("acme", words, config{})
+	if len(words.words) != 1 || words.words[0] != "acme" {
+		t.Errorf("words = %q, want exactly [\"acme\"]", words.words)
+	}
+
+	processWord("  ", words, config{})
+	if len(words.words) != 1 {
+		t.Errorf("words = %q after a blank input, want no blank queries", words.words)
+	}
+}
+
+func TestSortResultsMixedPlatforms(t *testing.T) {
+	day := func(d int) time.Time {
+		return time.Date(2021, time.March, d, 12, 0, 0, 0, time.UTC)
+	}
+	results := func() []dorky.Result {
+		return []dorky.Result{
+			{Platform: dorky.PlatformGitHub, Category: categoryOrg, Name: "acme"},
+			{Platform: dorky.PlatformGitLab, Category: categoryRepo, Name: "acme/platform/api", Stars: 7, LastActive: day(3), Score: 0.8},
+			{Platform: dorky.PlatformGitHub, Category: categoryRepo, Name: "Acme/web", Stars: 42, LastActive: day(1), Score: 0.9},
+			{Platform: dorky.PlatformGitLab, Category: categoryUser, Name: "acme-dev", Score: 0.9},
+			{Platform: dorky.PlatformGitHub, Category: categoryRepo, Name: "acme/cli", Stars: 7, LastActive: day(5), Score: 0.7},
+		}
+	}
+
+	tests := []struct {
+		key  string
+		want []string
+	}{
+		// Results without a timestamp, such as accounts, sort last in
+		// their original order.
+		{sortUpdated, []string{"acme/cli", "acme/platform/api", "Acme/web", "acme", "acme-dev"}},
+		{sortStars, []string{"Acme/web", "acme/platform/api", "acme/cli", "acme", "acme-dev"}},
+		{sortName, []string{"acme", "acme-dev", "acme/cli", "acme/platform/api", "Acme/web"}},
+		{sortScore, []string{"Acme/web", "acme-dev", "acme/platform/api", "acme/cli", "acme"}},
+	}
+	for _, tt := range tests {
+		sorted := results()
+		sortResults(sorted, tt.key)
+		if got := resultNames(sorted); strings.Join(got, "|") != strings.Join(tt.want, "|") {
+			t.Errorf("sorted by %s = %q, want %q", tt.key, got, tt.want)
+		}
+	}
+}
@@ -0,0 +1,61 @@
+package main
+
+import (
+	"io"
+	"os"
+	"path/filepath"
+	"sort"
+)
+
+// outDirFiles holds the -out-dir files opened so far, keyed by platform and
+// category. Files are only created once their category prints a result.
+var outDirFiles = make(map[string]*os.File)
+
+// outDirName returns the file name -out-dir uses for platform and category,
+// such as github-repos.txt.
+func outDirName(platform, category string) string {
+	name := category + "s"
+	if category == categoryCode {
+		name = category
+	}
+	return platform + "-" + name + ".txt"
+}
+
+// resultOutput returns the writer results for platform and category go to.
+// Without -out-dir that is output. It must be called with outputMu held.
+func resultOutput(platform, category string) io.Writer {
+	if flags.outDirFlag == "" {
+		return output
+	}
+
+	key := platform + "\x00" + category
+	if f, ok := outDirFiles[key]; ok {
+		return f
+	}
+
+	f, err := openOutputFile(filepath.Join(flags.outDirFlag, outDirName(platform, category)), flags.appendFlag)
+	if err != nil {
+		errorPrint("Error opening output file, writing %s to stdout instead: %s\n", categoryLabels[platform][category], err)
+		return output
+	}
+	outDirFiles[key] = f
+	return f
+}
+
+// closeOutDir closes every file opened for -out-dir, returning the first
+// error.
+func closeOutDir() error {
+	keys := make([]string, 0, len(outDirFiles))
+	for key := range outDirFiles {
+		keys = append(keys, key)
+	}
+	sort.Strings(keys)
+
+	var first error
+	for _, key := range keys {
+		if err := outDirFiles[key].Close(); err != nil && first == nil {
+			first = err
+		}
+	}
+	return first
+}
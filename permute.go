@@ -0,0 +1,64 @@
+package main
+
+import "strings"
+
+var (
+	permutationSeparators = []string{"-", "_", ""}
+	permutationSuffixes   = []string{"-dev", "-staging", "-api"}
+)
+
+// permutations combines the whitespace-separated tokens of inputs into new
+// candidate words: each token with the common suffixes, then every ordered
+// pair of distinct tokens joined by each separator. At most limit words are
+// returned, and none that duplicate a token.
+func permutations(inputs []string, limit int) []string {
+	var tokens []string
+	seenTokens := make(map[string]struct{})
+	for _, input := range inputs {
+		for _, token := range strings.Fields(input) {
+			if _, exists := seenTokens[token]; !exists {
+				seenTokens[token] = struct{}{}
+				tokens = append(tokens, token)
+			}
+		}
+	}
+
+	var generated []string
+	seenWords := make(map[string]struct{})
+	add := func(word string) bool {
+		if len(generated) >= limit {
+			return false
+		}
+		if _, exists := seenTokens[word]; exists {
+			return true
+		}
+		if _, exists := seenWords[word]; !exists {
+			seenWords[word] = struct{}{}
+			generated = append(generated, word)
+		}
+		return true
+	}
+
+	for _, token := range tokens {
+		for _, suffix := range permutationSuffixes {
+			if !add(token + suffix) {
+				return generated
+			}
+		}
+	}
+
+	for _, first := range tokens {
+		for _, second := range tokens {
+			if first == second {
+				continue
+			}
+			for _, sep := range permutationSeparators {
+				if !add(first + sep + second) {
+					return generated
+				}
+			}
+		}
+	}
+
+	return generated
+}
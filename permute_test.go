@@ -0,0 +1,48 @@
+package main
+
+import (
+	"strings"
+	"testing"
+)
+
+func TestPermutations(t *testing.T) {
+	tests := []struct {
+		name   string
+		inputs []string
+		limit  int
+		want   []string
+	}{
+		{"one token", []string{"acme"}, 100, []string{"acme-dev", "acme-staging", "acme-api"}},
+		{"two tokens", []string{"acme corp"}, 100, []string{
+			"acme-dev", "acme-staging", "acme-api", "corp-dev", "corp-staging", "corp-api",
+			"acme-corp", "acme_corp", "acmecorp", "corp-acme", "corp_acme", "corpacme",
+		}},
+		{"tokens across inputs, repeated", []string{"acme", "corp acme"}, 100, []string{
+			"acme-dev", "acme-staging", "acme-api", "corp-dev", "corp-staging", "corp-api",
+			"acme-corp", "acme_corp", "acmecorp", "corp-acme", "corp_acme", "corpacme",
+		}},
+		{"token already a permutation", []string{"acme api acme-api"}, 4, []string{"acme-dev", "acme-staging", "api-dev", "api-staging"}},
+		{"limit", []string{"acme corp"}, 5, []string{"acme-dev", "acme-staging", "acme-api", "corp-dev", "corp-staging"}},
+		{"zero limit", []string{"acme corp"}, 0, nil},
+		{"no input", nil, 100, nil},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			if got := permutations(tt.inputs, tt.limit); strings.Join(got, "|") != strings.Join(tt.want, "|") {
+				t.Errorf("permutations(%q, %d) = %q, want %q", tt.inputs, tt.limit, got, tt.want)
+			}
+		})
+	}
+}
+
+func TestPermutationsLimit(t *testing.T) {
+	inputs := []string{"alpha beta gamma delta epsilon zeta eta theta iota kappa"}
+	for _, limit := range []int{1, 10, 50, 1000} {
+		got := permutations(inputs, limit)
+		// 10 tokens give 30 suffixed words and 90 ordered pairs, joined
+		// three ways.
+		if want := min(limit, 30+90*3); len(got) != want {
+			t.Errorf("permutations with limit %d returned %d words, want %d", limit, len(got), want)
+		}
+	}
+}
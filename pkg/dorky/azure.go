@@ -0,0 +1,232 @@
+package dorky
+
+import (
+	"context"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"net/http"
+	"net/url"
+	"strings"
+	"sync"
+	"time"
+)
+
+const (
+	azureBaseURL    = "https://dev.azure.com/"
+	azureAPIVersion = "6.0"
+)
+
+var errAzureNotFound = errors.New("azure devops: not found")
+
+// AzureDevOpsClient is a minimal client for one Azure DevOps organization.
+// Azure DevOps has no search endpoint for projects or repositories, so the
+// organization's lists are fetched once and matched locally.
+type AzureDevOpsClient struct {
+	client *http.Client
+	orgURL string
+	token  string
+
+	mu       sync.Mutex
+	projects []azureProject
+	repos    []azureRepository
+}
+
+type azureProject struct {
+	Name           string    `json:"name"`
+	Description    string    `json:"description"`
+	LastUpdateTime time.Time `json:"lastUpdateTime"`
+}
+
+type azureRepository struct {
+	Name    string `json:"name"`
+	WebURL  string `json:"webUrl"`
+	Project struct {
+		Name string `json:"name"`
+	} `json:"project"`
+}
+
+// NewAzureDevOpsClient returns a client for the organization org, given as
+// either its name or its URL, authenticated with a personal access token.
+func NewAzureDevOpsClient(org, token string, opts ClientOptions) (*AzureDevOpsClient, error) {
+	if org == "" {
+		return nil, errors.New("Azure DevOps organization is empty")
+	}
+	if !strings.Contains(org, "://") {
+		org = azureBaseURL + url.PathEscape(org)
+	}
+	if err := ValidateBaseURL(org); err != nil {
+		return nil, fmt.Errorf("Azure DevOps organization URL %s", err)
+	}
+
+	if token == "" {
+		return nil, errors.New("Azure DevOps token is empty")
+	}
+
+	return &AzureDevOpsClient{
+		client: &http.Client{Transport: newTransport(opts.baseTransport(), nil, opts)},
+		orgURL: strings.TrimSuffix(org, "/"),
+		token:  token,
+	}, nil
+}
+
+// get fetches endpoint, relative to the organization URL, into v and returns
+// the continuation token for the next page, if any.
+func (c *AzureDevOpsClient) get(ctx context.Context, endpoint string, params url.Values, v interface{}) (string, error) {
+	params.Set("api-version", azureAPIVersion)
+	req, err := http.NewRequestWithContext(ctx, http.MethodGet, c.orgURL+"/"+endpoint+"?"+params.Encode(), nil)
+	if err != nil {
+		return "", err
+	}
+	req.SetBasicAuth("", c.token)
+	req.Header.Set("Accept", "application/json")
+
+	resp, err := c.client.Do(req)
+	if err != nil {
+		return "", err
+	}
+	defer resp.Body.Close()
+
+	if resp.StatusCode == http.StatusNotFound {
+		return "", errAzureNotFound
+	}
+	if resp.StatusCode < 200 || resp.StatusCode > 299 {
+		return "", authError(resp, fmt.Errorf("azure devops: unexpected response %s from %s", resp.Status, req.URL))
+	}
+	// Azure DevOps answers bad credentials with a sign-in page.
+	if !strings.HasPrefix(resp.Header.Get("Content-Type"), "application/json") {
+		return "", fmt.Errorf("%w: azure devops: sign-in page returned from %s", ErrAuth, req.URL)
+	}
+
+	return resp.Header.Get("X-Ms-Continuationtoken"), json.NewDecoder(resp.Body).Decode(v)
+}
+
+func (c *AzureDevOpsClient) listProjects(ctx context.Context) ([]azureProject, error) {
+	c.mu.Lock()
+	defer c.mu.Unlock()
+	if c.projects != nil {
+		return c.projects, nil
+	}
+
+	projects := []azureProject{}
+	params := url.Values{}
+	for {
+		var page struct {
+			Value []azureProject `json:"value"`
+		}
+		token, err := c.get(ctx, "_apis/projects", params, &page)
+		if err != nil {
+			return nil, err
+		}
+		projects = append(projects, page.Value...)
+
+		if token == "" {
+			break
+		}
+		params.Set("continuationToken", token)
+	}
+
+	c.projects = projects
+	return projects, nil
+}
+
+func (c *AzureDevOpsClient) listRepositories(ctx context.Context) ([]azureRepository, error) {
+	c.mu.Lock()
+	defer c.mu.Unlock()
+	if c.repos != nil {
+		return c.repos, nil
+	}
+
+	var list struct {
+		Value []azureRepository `json:"value"`
+	}
+	if _, err := c.get(ctx, "_apis/git/repositories", url.Values{}, &list); err != nil {
+		return nil, err
+	}
+
+	c.repos = append([]azureRepository{}, list.Value...)
+	return c.repos, nil
+}
+
+// SearchAzureDevOpsProjects returns up to maxResults projects in the
+// organization whose name contains query. Projects are the closest Azure
+// DevOps equivalent of an organization on other platforms.
+func (s *Searcher) SearchAzureDevOpsProjects(ctx context.Context, query string, maxResults int) ([]Result, error) {
+	reqCtx, cancel := s.requestContext(ctx)
+	projects, err := s.Azure.listProjects(reqCtx)
+	cancel()
+	if err != nil {
+		return nil, err
+	}
+
+	var results []Result
+	for _, project := range projects {
+		if containsFold(project.Name, query) {
+			results = append(results, Result{Platform: PlatformAzure, Category: CategoryOrg, Query: query, Name: project.Name,
+				URL: s.Azure.orgURL + "/" + url.PathEscape(project.Name), Description: project.Description, LastActive: project.LastUpdateTime})
+		}
+	}
+
+	results = truncate(results, maxResults)
+	emitPage(ctx, results, 0, maxResults)
+	return results, nil
+}
+
+// SearchAzureDevOpsRepositories returns up to maxResults repositories in the
+// organization whose name contains query, named "project/repo".
+func (s *Searcher) SearchAzureDevOpsRepositories(ctx context.Context, query string, maxResults int) ([]Result, error) {
+	reqCtx, cancel := s.requestContext(ctx)
+	repos, err := s.Azure.listRepositories(reqCtx)
+	cancel()
+	if err != nil {
+		return nil, err
+	}
+
+	var results []Result
+	for _, repo := range repos {
+		if containsFold(repo.Name, query) {
+			results = append(results, Result{Platform: PlatformAzure, Category: CategoryRepo, Query: query, Name: repo.Project.Name + "/" + repo.Name, URL: repo.WebURL})
+		}
+	}
+
+	results = truncate(results, maxResults)
+	emitPage(ctx, results, 0, maxResults)
+	return results, nil
+}
+
+func (s *Searcher) checkAzure(ctx context.Context, category, name string) ([]Result, error) {
+	reqCtx, cancel := s.requestContext(ctx)
+	defer cancel()
+
+	var found Result
+	var err error
+	switch category {
+	case CategoryOrg:
+		var project azureProject
+		if _, err = s.Azure.get(reqCtx, "_apis/projects/"+url.PathEscape(name), url.Values{}, &project); err == nil {
+			found = Result{Name: project.Name, URL: s.Azure.orgURL + "/" + url.PathEscape(project.Name), Description: project.Description, LastActive: project.LastUpdateTime}
+		}
+	case CategoryRepo:
+		projectName, repoName := splitRepoPath(name)
+		var repo azureRepository
+		if _, err = s.Azure.get(reqCtx, url.PathEscape(projectName)+"/_apis/git/repositories/"+url.PathEscape(repoName), url.Values{}, &repo); err == nil {
+			found = Result{Name: repo.Project.Name + "/" + repo.Name, URL: repo.WebURL}
+		}
+	default:
+		return nil, ErrUnsupported
+	}
+
+	if errors.Is(err, errAzureNotFound) {
+		return nil, nil
+	}
+	if err != nil {
+		return nil, err
+	}
+
+	found.Platform, found.Category, found.Query = PlatformAzure, category, name
+	return []Result{found}, nil
+}
+
+func containsFold(s, substr string) bool {
+	return strings.Contains(strings.ToLower(s), strings.ToLower(substr))
+}
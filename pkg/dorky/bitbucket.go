@@ -0,0 +1,189 @@
+package dorky
+
+import (
+	"context"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"net/http"
+	"net/url"
+	"strconv"
+	"time"
+)
+
+const bitbucketAPIURL = "https://api.bitbucket.org/2.0/"
+
+// Bitbucket Cloud pages hold at most 100 values.
+const bitbucketMaxPerPage = 100
+
+var errBitbucketNotFound = errors.New("bitbucket: not found")
+
+// BitbucketClient is a minimal client for the Bitbucket Cloud REST API.
+type BitbucketClient struct {
+	client   *http.Client
+	baseURL  string
+	username string
+	password string
+}
+
+type bitbucketLinks struct {
+	HTML struct {
+		Href string `json:"href"`
+	} `json:"html"`
+}
+
+type bitbucketRepository struct {
+	FullName    string         `json:"full_name"`
+	Description string         `json:"description"`
+	UpdatedOn   time.Time      `json:"updated_on"`
+	Language    string         `json:"language"`
+	Links       bitbucketLinks `json:"links"`
+
+	// Parent is only set on forks.
+	Parent *struct{} `json:"parent"`
+}
+
+type bitbucketRepositoryPage struct {
+	Values []bitbucketRepository `json:"values"`
+	Next   string                `json:"next"`
+}
+
+type bitbucketWorkspace struct {
+	Slug  string         `json:"slug"`
+	Links bitbucketLinks `json:"links"`
+}
+
+type bitbucketUser struct {
+	Username string         `json:"username"`
+	Nickname string         `json:"nickname"`
+	Links    bitbucketLinks `json:"links"`
+}
+
+// NewBitbucketClient returns a Bitbucket Cloud client authenticated with a
+// username and app password.
+func NewBitbucketClient(username, password string, opts ClientOptions) (*BitbucketClient, error) {
+	if username == "" || password == "" {
+		return nil, errors.New("Bitbucket username or app password is empty")
+	}
+
+	return &BitbucketClient{
+		client:   &http.Client{Transport: newTransport(opts.baseTransport(), nil, opts)},
+		baseURL:  bitbucketAPIURL,
+		username: username,
+		password: password,
+	}, nil
+}
+
+func (c *BitbucketClient) get(ctx context.Context, endpoint string, v interface{}) error {
+	req, err := http.NewRequestWithContext(ctx, http.MethodGet, endpoint, nil)
+	if err != nil {
+		return err
+	}
+	req.SetBasicAuth(c.username, c.password)
+	req.Header.Set("Accept", "application/json")
+
+	resp, err := c.client.Do(req)
+	if err != nil {
+		return err
+	}
+	defer resp.Body.Close()
+
+	if resp.StatusCode == http.StatusNotFound {
+		return errBitbucketNotFound
+	}
+	if resp.StatusCode < 200 || resp.StatusCode > 299 {
+		return authError(resp, fmt.Errorf("bitbucket: unexpected response %s from %s", resp.Status, endpoint))
+	}
+
+	return json.NewDecoder(resp.Body).Decode(v)
+}
+
+// SearchBitbucketWorkspaces returns the workspace named query, if it exists.
+// Bitbucket Cloud has no search endpoint for workspaces or users, so those
+// categories are resolved with a direct lookup of the query.
+func (s *Searcher) SearchBitbucketWorkspaces(ctx context.Context, query string) ([]Result, error) {
+	return s.lookup(ctx, s.checkBitbucket, CategoryOrg, query)
+}
+
+// SearchBitbucketRepositories returns up to maxResults repositories whose name
+// contains query.
+func (s *Searcher) SearchBitbucketRepositories(ctx context.Context, query string, maxResults int) ([]Result, error) {
+	params := url.Values{}
+	params.Set("q", SearchQuery(PlatformBitbucket, CategoryRepo, query))
+	params.Set("pagelen", strconv.Itoa(perPage(maxResults, bitbucketMaxPerPage)))
+	endpoint := s.Bitbucket.baseURL + "repositories?" + params.Encode()
+
+	var repos []Result
+	for endpoint != "" {
+		var page bitbucketRepositoryPage
+		reqCtx, cancel := s.requestContext(ctx)
+		err := s.Bitbucket.get(reqCtx, endpoint, &page)
+		cancel()
+		if err != nil {
+			return nil, err
+		}
+
+		from := len(repos)
+		for _, repo := range page.Values {
+			repos = append(repos, Result{Platform: PlatformBitbucket, Category: CategoryRepo, Query: query, Name: repo.FullName, URL: repo.Links.HTML.Href,
+				Description: repo.Description, LastActive: repo.UpdatedOn, Fork: repo.Parent != nil, Language: repo.Language})
+		}
+		emitPage(ctx, repos, from, maxResults)
+
+		if len(repos) >= maxResults {
+			break
+		}
+		endpoint = page.Next
+	}
+
+	return truncate(repos, maxResults), nil
+}
+
+// SearchBitbucketUsers returns the user named query, if it exists, found by
+// their personal workspace.
+func (s *Searcher) SearchBitbucketUsers(ctx context.Context, query string) ([]Result, error) {
+	return s.lookup(ctx, s.checkBitbucket, CategoryUser, query)
+}
+
+func (s *Searcher) checkBitbucket(ctx context.Context, category, name string) ([]Result, error) {
+	reqCtx, cancel := s.requestContext(ctx)
+	defer cancel()
+
+	var found Result
+	var err error
+	switch category {
+	case CategoryOrg:
+		var workspace bitbucketWorkspace
+		if err = s.Bitbucket.get(reqCtx, s.Bitbucket.baseURL+"workspaces/"+url.PathEscape(name), &workspace); err == nil {
+			found = Result{Name: workspace.Slug, URL: workspace.Links.HTML.Href}
+		}
+	case CategoryRepo:
+		workspace, slug := splitRepoPath(name)
+		var repo bitbucketRepository
+		if err = s.Bitbucket.get(reqCtx, s.Bitbucket.baseURL+"repositories/"+url.PathEscape(workspace)+"/"+url.PathEscape(slug), &repo); err == nil {
+			found = Result{Name: repo.FullName, URL: repo.Links.HTML.Href, Description: repo.Description, LastActive: repo.UpdatedOn, Fork: repo.Parent != nil,
+				Language: repo.Language}
+		}
+	case CategoryUser:
+		// users/{selected_user} only accepts an account ID or UUID, but
+		// every user has a personal workspace named after their username.
+		// Shared workspaces can't be told apart from personal ones, so they
+		// match too.
+		var workspace bitbucketWorkspace
+		if err = s.Bitbucket.get(reqCtx, s.Bitbucket.baseURL+"workspaces/"+url.PathEscape(name), &workspace); err == nil {
+			found = Result{Name: workspace.Slug, URL: workspace.Links.HTML.Href}
+		}
+	default:
+		return nil, ErrUnsupported
+	}
+
+	if errors.Is(err, errBitbucketNotFound) {
+		return nil, nil
+	}
+	if err != nil {
+		return nil, err
+	}
+
+	found.Platform, found.Category, found.Query = PlatformBitbucket, category, name
+	return []Result{found}, nil
+}
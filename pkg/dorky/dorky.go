@@ -0,0 +1,459 @@
+// Package dorky searches code hosting platforms for organizations,
+// repositories, and users matching a query. It is the library behind the
+// dorky command, returning structured results instead of printing them.
+package dorky
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"net/url"
+	"strconv"
+	"strings"
+	"sync"
+	"time"
+
+	"github.com/google/go-github/v38/github"
+	"github.com/xanzy/go-gitlab"
+)
+
+// Platforms and categories identify where a Result came from.
+const (
+	PlatformGitHub    = "github"
+	PlatformGitLab    = "gitlab"
+	PlatformBitbucket = "bitbucket"
+	PlatformGitea     = "gitea"
+	PlatformAzure     = "azure"
+	PlatformSourceHut = "sourcehut"
+
+	CategoryOrg     = "org"
+	CategoryRepo    = "repo"
+	CategoryUser    = "user"
+	CategoryCode    = "code"
+	CategoryGist    = "gist"
+	CategoryTopic   = "topic"
+	CategorySnippet = "snippet"
+)
+
+// ErrUnsupported is returned when a platform does not support a category or
+// has no client configured.
+var ErrUnsupported = errors.New("dorky: unsupported platform or category")
+
+// ErrAuth is returned when a platform rejects the configured credentials.
+var ErrAuth = errors.New("dorky: token invalid or lacks scope")
+
+// Result is a single match returned by a search or check. Description,
+// Stars, and LastActive are left empty when the platform doesn't report them.
+type Result struct {
+	Platform    string
+	Category    string
+	Query       string
+	Name        string
+	URL         string
+	Description string
+	Stars       int
+	LastActive  time.Time
+
+	// Fork reports whether a repository is a fork of another, and Archived
+	// whether it has been archived.
+	Fork     bool
+	Archived bool
+
+	// Language is a repository's primary language, on platforms that
+	// report one.
+	Language string
+
+	// DisplayName is a user's full name, when the platform returned one.
+	// GitHub's user search does not; looking the user up does.
+	DisplayName string
+
+	// Company, Blog, Email, and PublicRepos describe a user whose profile
+	// was looked up, with ResolveUser or by checking the name directly.
+	Company     string
+	Blog        string
+	Email       string
+	PublicRepos int
+
+	// Instance is the Instance of the Searcher that found the result.
+	Instance string
+
+	// Score is how closely Name matches Query, from 0 to 1. Searches leave
+	// it unset; callers fill it in with Similarity when they need it.
+	Score float64
+}
+
+// Searcher runs searches against the platforms whose clients are set. A nil
+// client disables that platform. Clients should be created once and shared so
+// their rate limiters apply across every search.
+type Searcher struct {
+	GitHub    *github.Client
+	GitLab    *gitlab.Client
+	Bitbucket *BitbucketClient
+	Gitea     *GiteaClient
+	Azure     *AzureDevOpsClient
+	SourceHut *SourceHutClient
+
+	// Org, if set, restricts GitHub repository searches to this
+	// organization and GitLab project searches to this group and its
+	// subgroups. User likewise restricts them to one account's
+	// repositories. At most one of the two should be set.
+	Org  string
+	User string
+
+	// MinStars, if positive, restricts GitHub repository searches to
+	// repositories with at least this many stars.
+	MinStars int
+
+	// Instance, if set, names the host this Searcher's clients talk to, such
+	// as gitlab.example.com. It is copied to every Result, so results from
+	// Searchers for different instances of one platform can be told apart.
+	Instance string
+
+	// CombineGitHubAccounts makes GitHub organization and user searches for
+	// the same query share one user search, partitioned by account type,
+	// instead of sending two. Set it when both categories are searched.
+	CombineGitHubAccounts bool
+
+	// Timeout bounds each API request. Zero means no timeout.
+	Timeout time.Duration
+
+	// Logf, if set, receives progress messages such as GitHub's remaining
+	// rate limit after each request.
+	Logf func(format string, a ...interface{})
+
+	// RateFunc, if set, is called with the remaining requests and the limit
+	// a platform reported after each request, so callers can pace
+	// themselves. Only GitHub reports them.
+	RateFunc func(platform string, remaining, limit int)
+
+	// accountsMu guards accounts, the half of each combined GitHub account
+	// search not yet returned, keyed by category and query.
+	accountsMu sync.Mutex
+	accounts   map[string]githubAccounts
+}
+
+// Platforms returns the platforms that have a client configured, in a stable
+// order.
+func (s *Searcher) Platforms() []string {
+	var platforms []string
+	if s.GitHub != nil {
+		platforms = append(platforms, PlatformGitHub)
+	}
+	if s.GitLab != nil {
+		platforms = append(platforms, PlatformGitLab)
+	}
+	if s.Bitbucket != nil {
+		platforms = append(platforms, PlatformBitbucket)
+	}
+	if s.Gitea != nil {
+		platforms = append(platforms, PlatformGitea)
+	}
+	if s.Azure != nil {
+		platforms = append(platforms, PlatformAzure)
+	}
+	if s.SourceHut != nil {
+		platforms = append(platforms, PlatformSourceHut)
+	}
+	return platforms
+}
+
+// Search returns up to maxResults matches for query in one platform and
+// category.
+func (s *Searcher) Search(ctx context.Context, platform, category, query string, maxResults int) ([]Result, error) {
+	results, err := s.search(ctx, platform, category, query, maxResults)
+	return s.label(results), err
+}
+
+// label sets the Instance of each result to s.Instance.
+func (s *Searcher) label(results []Result) []Result {
+	if s.Instance != "" {
+		for i := range results {
+			results[i].Instance = s.Instance
+		}
+	}
+	return results
+}
+
+func (s *Searcher) search(ctx context.Context, platform, category, query string, maxResults int) ([]Result, error) {
+	switch {
+	case platform == PlatformGitHub && s.GitHub != nil:
+		switch category {
+		case CategoryOrg:
+			return s.SearchGitHubOrganizations(ctx, query, maxResults)
+		case CategoryRepo:
+			return s.SearchGitHubRepositories(ctx, query, maxResults)
+		case CategoryUser:
+			return s.SearchGitHubUsers(ctx, query, maxResults)
+		case CategoryCode:
+			return s.SearchGitHubCode(ctx, query, maxResults)
+		case CategoryGist:
+			return s.ListGitHubGists(ctx, query, maxResults)
+		case CategoryTopic:
+			return s.SearchGitHubTopics(ctx, query, maxResults)
+		}
+	case platform == PlatformGitLab && s.GitLab != nil:
+		switch category {
+		case CategoryOrg:
+			return s.SearchGitLabGroups(ctx, query, maxResults)
+		case CategoryRepo:
+			return s.SearchGitLabProjects(ctx, query, maxResults)
+		case CategoryUser:
+			return s.SearchGitLabUsers(ctx, query, maxResults)
+		case CategoryTopic:
+			return s.SearchGitLabTopics(ctx, query, maxResults)
+		case CategorySnippet:
+			return s.SearchGitLabSnippets(ctx, query, maxResults)
+		}
+	case platform == PlatformBitbucket && s.Bitbucket != nil:
+		switch category {
+		case CategoryOrg:
+			return s.SearchBitbucketWorkspaces(ctx, query)
+		case CategoryRepo:
+			return s.SearchBitbucketRepositories(ctx, query, maxResults)
+		case CategoryUser:
+			return s.SearchBitbucketUsers(ctx, query)
+		}
+	case platform == PlatformGitea && s.Gitea != nil:
+		switch category {
+		case CategoryOrg:
+			return s.SearchGiteaOrganizations(ctx, query)
+		case CategoryRepo:
+			return s.SearchGiteaRepositories(ctx, query, maxResults)
+		case CategoryUser:
+			return s.SearchGiteaUsers(ctx, query, maxResults)
+		}
+	case platform == PlatformAzure && s.Azure != nil:
+		switch category {
+		case CategoryOrg:
+			return s.SearchAzureDevOpsProjects(ctx, query, maxResults)
+		case CategoryRepo:
+			return s.SearchAzureDevOpsRepositories(ctx, query, maxResults)
+		}
+	case platform == PlatformSourceHut && s.SourceHut != nil:
+		switch category {
+		case CategoryRepo:
+			return s.SearchSourceHutRepositories(ctx, query, maxResults)
+		case CategoryUser:
+			return s.SearchSourceHutUsers(ctx, query)
+		}
+	}
+	return nil, fmt.Errorf("%w: %s/%s", ErrUnsupported, platform, category)
+}
+
+// ListOrgRepositories returns up to maxResults repositories owned by the
+// organization or group named org on platform, as found by an organization
+// search.
+func (s *Searcher) ListOrgRepositories(ctx context.Context, platform, org string, maxResults int) ([]Result, error) {
+	var results []Result
+	var err error
+	switch {
+	case platform == PlatformGitHub && s.GitHub != nil:
+		results, err = s.ListGitHubOrgRepositories(ctx, org, maxResults)
+	case platform == PlatformGitLab && s.GitLab != nil:
+		results, err = s.ListGitLabGroupProjects(ctx, org, maxResults)
+	default:
+		return nil, fmt.Errorf("%w: %s/%s", ErrUnsupported, platform, CategoryOrg)
+	}
+	return s.label(results), err
+}
+
+// ResolveUser looks up the full profile of the user r names and returns r
+// with its profile fields filled in.
+func (s *Searcher) ResolveUser(ctx context.Context, r Result) (Result, error) {
+	switch {
+	case r.Platform == PlatformGitHub && s.GitHub != nil:
+		return s.resolveGitHubUser(ctx, r)
+	}
+	return r, fmt.Errorf("%w: %s/%s", ErrUnsupported, r.Platform, CategoryUser)
+}
+
+// platformCategories lists the categories each platform supports.
+var platformCategories = map[string][]string{
+	PlatformGitHub:    {CategoryOrg, CategoryRepo, CategoryUser, CategoryCode, CategoryGist, CategoryTopic},
+	PlatformGitLab:    {CategoryOrg, CategoryRepo, CategoryUser, CategoryTopic, CategorySnippet},
+	PlatformBitbucket: {CategoryOrg, CategoryRepo, CategoryUser},
+	PlatformGitea:     {CategoryOrg, CategoryRepo, CategoryUser},
+	PlatformAzure:     {CategoryOrg, CategoryRepo},
+	PlatformSourceHut: {CategoryRepo, CategoryUser},
+}
+
+// Supported reports whether platform can be searched for category.
+func Supported(platform, category string) bool {
+	for _, c := range platformCategories[platform] {
+		if c == category {
+			return true
+		}
+	}
+	return false
+}
+
+// SearchStream is like Search but also passes results to page as each page
+// arrives, so callers can show them before the search completes. Every result
+// Search returns is passed to page exactly once, in order.
+func (s *Searcher) SearchStream(ctx context.Context, platform, category, query string, maxResults int, page func([]Result)) ([]Result, error) {
+	labeled := func(results []Result) {
+		page(s.label(results))
+	}
+	return s.Search(context.WithValue(ctx, pageFuncKey{}, labeled), platform, category, query, maxResults)
+}
+
+type pageFuncKey struct{}
+
+// emitPage passes results[from:], capped at maxResults, to the SearchStream
+// callback in ctx, if any.
+func emitPage(ctx context.Context, results []Result, from, maxResults int) {
+	page, ok := ctx.Value(pageFuncKey{}).(func([]Result))
+	if !ok || page == nil {
+		return
+	}
+	if results = truncate(results, maxResults); from < len(results) {
+		page(results[from:])
+	}
+}
+
+// lookup resolves a search on platforms without a search endpoint by checking
+// for query directly.
+func (s *Searcher) lookup(ctx context.Context, check func(context.Context, string, string) ([]Result, error), category, query string) ([]Result, error) {
+	results, err := check(ctx, category, query)
+	if err != nil {
+		return nil, err
+	}
+	emitPage(ctx, results, 0, len(results))
+	return results, nil
+}
+
+// Authenticate makes one cheap authenticated request to platform, so bad
+// credentials can be reported before any searches run. Rejected credentials
+// are reported as ErrAuth.
+func (s *Searcher) Authenticate(ctx context.Context, platform string) error {
+	reqCtx, cancel := s.requestContext(ctx)
+	defer cancel()
+
+	switch {
+	case platform == PlatformGitHub && s.GitHub != nil:
+		return s.authGitHub(reqCtx)
+	case platform == PlatformGitLab && s.GitLab != nil:
+		return s.authGitLab(reqCtx)
+	case platform == PlatformBitbucket && s.Bitbucket != nil:
+		return s.Bitbucket.get(reqCtx, s.Bitbucket.baseURL+"user", &bitbucketUser{})
+	case platform == PlatformGitea && s.Gitea != nil:
+		if err := reqCtx.Err(); err != nil {
+			return err
+		}
+		_, resp, err := s.Gitea.GetMyUserInfo()
+		if resp != nil {
+			return authError(resp.Response, err)
+		}
+		return err
+	case platform == PlatformAzure && s.Azure != nil:
+		_, err := s.Azure.get(reqCtx, "_apis/projects", url.Values{"$top": {"1"}}, &struct{}{})
+		return err
+	case platform == PlatformSourceHut && s.SourceHut != nil:
+		_, err := s.SourceHut.user(reqCtx, sourceHutMeQuery, nil)
+		return err
+	}
+	return fmt.Errorf("%w: %s", ErrUnsupported, platform)
+}
+
+// Check looks name up directly and returns it only if it exists. A missing
+// entity is not an error. Repository checks expect name in "owner/repo" form
+// and return no results otherwise.
+func (s *Searcher) Check(ctx context.Context, platform, category, name string) ([]Result, error) {
+	results, err := s.check(ctx, platform, category, name)
+	return s.label(results), err
+}
+
+func (s *Searcher) check(ctx context.Context, platform, category, name string) ([]Result, error) {
+	if category == CategoryRepo && !isRepoPath(name) {
+		return nil, nil
+	}
+
+	switch {
+	case platform == PlatformGitHub && s.GitHub != nil:
+		return s.checkGitHub(ctx, category, name)
+	case platform == PlatformGitLab && s.GitLab != nil:
+		return s.checkGitLab(ctx, category, name)
+	case platform == PlatformBitbucket && s.Bitbucket != nil:
+		return s.checkBitbucket(ctx, category, name)
+	case platform == PlatformGitea && s.Gitea != nil:
+		return s.checkGitea(ctx, category, name)
+	case platform == PlatformAzure && s.Azure != nil:
+		return s.checkAzure(ctx, category, name)
+	case platform == PlatformSourceHut && s.SourceHut != nil:
+		return s.checkSourceHut(ctx, category, name)
+	}
+	return nil, fmt.Errorf("%w: %s/%s", ErrUnsupported, platform, category)
+}
+
+// SearchQuery returns the search string Search sends to platform for query
+// in category. Categories resolved by a direct lookup return query unchanged.
+func SearchQuery(platform, category, query string) string {
+	switch {
+	case platform == PlatformGitHub && category == CategoryOrg:
+		return "type:org " + query
+	case platform == PlatformGitHub && category == CategoryUser:
+		return "type:user " + query
+	case platform == PlatformBitbucket && category == CategoryRepo:
+		return fmt.Sprintf(`name ~ "%s"`, strings.ReplaceAll(query, `"`, `\"`))
+	}
+	return query
+}
+
+// RepoQuery returns the repository search string sent to GitHub for query
+// when searches are restricted to org or user, or to repositories with at
+// least minStars stars. With none of them it returns query unchanged.
+func RepoQuery(org, user, query string, minStars int) string {
+	switch {
+	case org != "":
+		query = "org:" + org + " " + query
+	case user != "":
+		query = "user:" + user + " " + query
+	}
+	if minStars > 0 {
+		query += " stars:>=" + strconv.Itoa(minStars)
+	}
+	return query
+}
+
+func (s *Searcher) requestContext(ctx context.Context) (context.Context, context.CancelFunc) {
+	if s.Timeout == 0 {
+		return context.WithCancel(ctx)
+	}
+	return context.WithTimeout(ctx, s.Timeout)
+}
+
+// Both GitHub and GitLab cap page sizes at 100 items.
+const maxPerPage = 100
+
+// perPage returns the page size for fetching maxResults, at most limit and
+// at least 1.
+func perPage(maxResults, limit int) int {
+	return max(min(maxResults, limit), 1)
+}
+
+// truncate returns at most maxResults of results, and none if maxResults is
+// not positive.
+func truncate(results []Result, maxResults int) []Result {
+	if maxResults <= 0 {
+		return results[:0]
+	}
+	if len(results) > maxResults {
+		return results[:maxResults]
+	}
+	return results
+}
+
+func isRepoPath(name string) bool {
+	owner, repo := splitRepoPath(name)
+	return owner != "" && repo != ""
+}
+
+// splitRepoPath splits "owner/name" at the last slash so GitLab-style nested
+// namespaces keep their full owner path.
+func splitRepoPath(name string) (owner, repo string) {
+	i := strings.LastIndex(name, "/")
+	if i < 0 {
+		return "", ""
+	}
+	return name[:i], name[i+1:]
+}
@@ -0,0 +1,42 @@
+package dorky
+
+import "testing"
+
+func TestTruncate(t *testing.T) {
+	results := []Result{{Name: "a"}, {Name: "b"}, {Name: "c"}}
+	tests := []struct {
+		maxResults int
+		want       int
+	}{
+		{5, 3},
+		{3, 3},
+		{2, 2},
+		{0, 0},
+		{-1, 0},
+	}
+	for _, tt := range tests {
+		if got := truncate(results, tt.maxResults); len(got) != tt.want {
+			t.Errorf("truncate to %d returned %d results, want %d", tt.maxResults, len(got), tt.want)
+		}
+	}
+	if got := truncate(nil, -1); len(got) != 0 {
+		t.Errorf("truncate(nil, -1) = %v, want no results", got)
+	}
+}
+
+func TestPerPage(t *testing.T) {
+	tests := []struct {
+		maxResults, want int
+	}{
+		{10, 10},
+		{100, 100},
+		{500, 100},
+		{0, 1},
+		{-1, 1},
+	}
+	for _, tt := range tests {
+		if got := perPage(tt.maxResults, maxPerPage); got != tt.want {
+			t.Errorf("perPage(%d, %d) = %d, want %d", tt.maxResults, maxPerPage, got, tt.want)
+		}
+	}
+}
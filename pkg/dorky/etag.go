@@ -0,0 +1,116 @@
+package dorky
+
+import (
+	"bytes"
+	"crypto/sha256"
+	"encoding/hex"
+	"encoding/json"
+	"io"
+	"net/http"
+	"os"
+	"path/filepath"
+
+	"github.com/codingo/dorky/internal/atomicfile"
+)
+
+// etagEntry is a stored response, kept so a 304 Not Modified can be answered
+// without downloading the body again.
+type etagEntry struct {
+	ETag   string      `json:"etag"`
+	Header http.Header `json:"header"`
+	Body   []byte      `json:"body"`
+}
+
+// etagTransport makes GET requests conditional. Responses carrying an ETag
+// are stored in dir, later requests for the same URL send it back in
+// If-None-Match, and a 304 is answered from the store. GitHub does not count
+// 304 responses against the rate limit.
+type etagTransport struct {
+	transport http.RoundTripper
+	dir       string
+	logf      func(format string, a ...interface{})
+}
+
+func (t *etagTransport) RoundTrip(req *http.Request) (*http.Response, error) {
+	if req.Method != http.MethodGet || req.Header.Get("If-None-Match") != "" {
+		return t.transport.RoundTrip(req)
+	}
+
+	key := etagKey(req)
+	entry, cached := t.load(key)
+	if cached {
+		req = req.Clone(req.Context())
+		req.Header.Set("If-None-Match", entry.ETag)
+	}
+
+	resp, err := t.transport.RoundTrip(req)
+	if err != nil {
+		return nil, err
+	}
+
+	if resp.StatusCode == http.StatusNotModified && cached {
+		io.Copy(io.Discard, resp.Body)
+		resp.Body.Close()
+
+		// The stored headers describe the body, but the fresh ones carry the
+		// current rate limit budget.
+		header := entry.Header.Clone()
+		for name, values := range resp.Header {
+			header[name] = values
+		}
+		resp.StatusCode = http.StatusOK
+		resp.Status = "200 OK"
+		resp.Header = header
+		resp.Body = io.NopCloser(bytes.NewReader(entry.Body))
+		resp.ContentLength = int64(len(entry.Body))
+		return resp, nil
+	}
+
+	etag := resp.Header.Get("ETag")
+	if resp.StatusCode != http.StatusOK || etag == "" {
+		return resp, nil
+	}
+
+	body, err := io.ReadAll(resp.Body)
+	resp.Body.Close()
+	if err != nil {
+		return nil, err
+	}
+	resp.Body = io.NopCloser(bytes.NewReader(body))
+
+	if err := t.store(key, etagEntry{ETag: etag, Header: resp.Header, Body: body}); err != nil {
+		t.logf("Error writing ETag cache: %s\n", err)
+	}
+	return resp, nil
+}
+
+// etagKey names the stored response for req. The Accept header is part of
+// the key because some APIs return a different representation for it, and
+// the credentials because another token may see different results, such as
+// private repositories.
+func etagKey(req *http.Request) string {
+	sum := sha256.Sum256([]byte(req.URL.String() + "\x00" + req.Header.Get("Accept") + "\x00" +
+		req.Header.Get("Authorization") + "\x00" + req.Header.Get("PRIVATE-TOKEN")))
+	return hex.EncodeToString(sum[:]) + ".json"
+}
+
+func (t *etagTransport) load(key string) (etagEntry, bool) {
+	var entry etagEntry
+	data, err := os.ReadFile(filepath.Join(t.dir, key))
+	if err != nil {
+		return entry, false
+	}
+	if err := json.Unmarshal(data, &entry); err != nil || entry.ETag == "" {
+		return entry, false
+	}
+	return entry, true
+}
+
+// store saves entry under key.
+func (t *etagTransport) store(key string, entry etagEntry) error {
+	data, err := json.Marshal(entry)
+	if err != nil {
+		return err
+	}
+	return atomicfile.Write(t.dir, key, data)
+}
@@ -0,0 +1,46 @@
+package dorky
+
+import (
+	"io"
+	"net/http"
+	"strings"
+	"testing"
+)
+
+func TestETagTransportSeparatesCredentials(t *testing.T) {
+	var revalidated []string
+	stub := roundTripFunc(func(req *http.Request) (*http.Response, error) {
+		auth := req.Header.Get("Authorization")
+		if req.Header.Get("If-None-Match") != "" {
+			revalidated = append(revalidated, auth)
+			return &http.Response{StatusCode: http.StatusNotModified, Header: http.Header{}, Body: http.NoBody}, nil
+		}
+		header := http.Header{"Etag": {`"v1"`}}
+		return &http.Response{StatusCode: http.StatusOK, Header: header, Body: io.NopCloser(strings.NewReader("body for " + auth))}, nil
+	})
+	transport := &etagTransport{transport: stub, dir: t.TempDir(), logf: func(string, ...interface{}) {}}
+
+	get := func(auth string) string {
+		t.Helper()
+		req, _ := http.NewRequest(http.MethodGet, "https://api.github.com/search/users?q=acme", nil)
+		req.Header.Set("Authorization", auth)
+		resp, err := transport.RoundTrip(req)
+		if err != nil {
+			t.Fatal(err)
+		}
+		defer resp.Body.Close()
+		body, _ := io.ReadAll(resp.Body)
+		return string(body)
+	}
+
+	get("token a")
+	if body := get("token b"); body != "body for token b" {
+		t.Errorf("token b got %q, want its own response", body)
+	}
+	if body := get("token a"); body != "body for token a" {
+		t.Errorf("token a got %q, want its stored response", body)
+	}
+	if len(revalidated) != 1 || revalidated[0] != "token a" {
+		t.Errorf("revalidated requests for %q, want only token a's second request", revalidated)
+	}
+}
@@ -0,0 +1,160 @@
+package dorky
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"net/http"
+	"strings"
+
+	"code.gitea.io/sdk/gitea"
+)
+
+// Gitea's default MAX_RESPONSE_ITEMS setting caps page sizes at 50.
+const giteaMaxPerPage = 50
+
+// GiteaClient is a Gitea or Forgejo client that also remembers the instance
+// URL, which the API omits from user and organization responses.
+type GiteaClient struct {
+	*gitea.Client
+	baseURL string
+}
+
+// NewGiteaClient returns a client for the Gitea or Forgejo instance at
+// baseURL, authenticated with token.
+func NewGiteaClient(baseURL, token string, opts ClientOptions) (*GiteaClient, error) {
+	if err := ValidateBaseURL(baseURL); err != nil {
+		return nil, fmt.Errorf("Gitea URL %s", err)
+	}
+
+	if token == "" {
+		return nil, errors.New("Gitea token is empty")
+	}
+
+	// The Gitea SDK binds a single context to the client rather than taking
+	// one per call, so the request timeout is enforced by the HTTP client.
+	hc := &http.Client{
+		Transport: newTransport(opts.baseTransport(), nil, opts),
+		Timeout:   opts.Timeout,
+	}
+
+	client, err := gitea.NewClient(baseURL, gitea.SetToken(token), gitea.SetHTTPClient(hc))
+	if err != nil {
+		return nil, err
+	}
+
+	return &GiteaClient{Client: client, baseURL: strings.TrimSuffix(baseURL, "/")}, nil
+}
+
+// SearchGiteaOrganizations returns the organization named query, if it
+// exists. Gitea has no organization search endpoint, so organizations are
+// resolved with a direct lookup of the query.
+func (s *Searcher) SearchGiteaOrganizations(ctx context.Context, query string) ([]Result, error) {
+	return s.lookup(ctx, s.checkGitea, CategoryOrg, query)
+}
+
+// SearchGiteaRepositories returns up to maxResults repositories matching
+// query.
+func (s *Searcher) SearchGiteaRepositories(ctx context.Context, query string, maxResults int) ([]Result, error) {
+	var repos []Result
+	opt := gitea.SearchRepoOptions{
+		ListOptions: gitea.ListOptions{Page: 1, PageSize: perPage(maxResults, giteaMaxPerPage)},
+		Keyword:     query,
+	}
+	for {
+		// The Gitea SDK takes no context, so cancellation is only noticed
+		// between pages.
+		if err := ctx.Err(); err != nil {
+			return nil, err
+		}
+		page, _, err := s.Gitea.SearchRepos(opt)
+		if err != nil {
+			return nil, err
+		}
+
+		from := len(repos)
+		for _, repo := range page {
+			repos = append(repos, Result{Platform: PlatformGitea, Category: CategoryRepo, Query: query, Name: repo.FullName, URL: repo.HTMLURL,
+				Description: repo.Description, Stars: repo.Stars, LastActive: repo.Updated, Fork: repo.Fork, Archived: repo.Archived})
+		}
+		emitPage(ctx, repos, from, maxResults)
+
+		if len(repos) >= maxResults || len(page) < opt.PageSize {
+			break
+		}
+		opt.Page++
+	}
+
+	return truncate(repos, maxResults), nil
+}
+
+// SearchGiteaUsers returns up to maxResults users matching query.
+func (s *Searcher) SearchGiteaUsers(ctx context.Context, query string, maxResults int) ([]Result, error) {
+	var users []Result
+	opt := gitea.SearchUsersOption{
+		ListOptions: gitea.ListOptions{Page: 1, PageSize: perPage(maxResults, giteaMaxPerPage)},
+		KeyWord:     query,
+	}
+	for {
+		if err := ctx.Err(); err != nil {
+			return nil, err
+		}
+		page, _, err := s.Gitea.SearchUsers(opt)
+		if err != nil {
+			return nil, err
+		}
+
+		from := len(users)
+		for _, user := range page {
+			users = append(users, Result{Platform: PlatformGitea, Category: CategoryUser, Query: query, Name: user.UserName, URL: s.Gitea.baseURL + "/" + user.UserName, Description: user.Description})
+		}
+		emitPage(ctx, users, from, maxResults)
+
+		if len(users) >= maxResults || len(page) < opt.PageSize {
+			break
+		}
+		opt.Page++
+	}
+
+	return truncate(users, maxResults), nil
+}
+
+func (s *Searcher) checkGitea(ctx context.Context, category, name string) ([]Result, error) {
+	if err := ctx.Err(); err != nil {
+		return nil, err
+	}
+
+	var found Result
+	var resp *gitea.Response
+	var err error
+	switch category {
+	case CategoryOrg:
+		var org *gitea.Organization
+		if org, resp, err = s.Gitea.GetOrg(name); err == nil {
+			found = Result{Name: org.UserName, URL: s.Gitea.baseURL + "/" + org.UserName, Description: org.Description}
+		}
+	case CategoryRepo:
+		owner, repoName := splitRepoPath(name)
+		var repo *gitea.Repository
+		if repo, resp, err = s.Gitea.GetRepo(owner, repoName); err == nil {
+			found = Result{Name: repo.FullName, URL: repo.HTMLURL, Description: repo.Description, Stars: repo.Stars, LastActive: repo.Updated, Fork: repo.Fork, Archived: repo.Archived}
+		}
+	case CategoryUser:
+		var user *gitea.User
+		if user, resp, err = s.Gitea.GetUserInfo(name); err == nil {
+			found = Result{Name: user.UserName, URL: s.Gitea.baseURL + "/" + user.UserName, Description: user.Description}
+		}
+	default:
+		return nil, ErrUnsupported
+	}
+
+	if err != nil {
+		if resp != nil && isNotFound(resp.Response) {
+			return nil, nil
+		}
+		return nil, err
+	}
+
+	found.Platform, found.Category, found.Query = PlatformGitea, category, name
+	return []Result{found}, nil
+}
@@ -0,0 +1,612 @@
+package dorky
+
+import (
+	"context"
+	"errors"
+	"io"
+	"math"
+	"net/http"
+	"strconv"
+	"strings"
+	"sync"
+	"time"
+
+	"github.com/google/go-github/v38/github"
+	"golang.org/x/oauth2"
+	"golang.org/x/time/rate"
+)
+
+const githubTopicURL = "https://github.com/topics/"
+
+// GitHub allows authenticated clients 30 search requests a minute, and 10 a
+// minute for code search. Pacing searches to those rates keeps them from
+// hitting the limits at all. Other API requests have a far larger budget and
+// are not paced.
+var (
+	githubSearchRate     = rate.Every(time.Minute / 30)
+	githubCodeSearchRate = rate.Every(time.Minute / 10)
+)
+
+// NewGitHubClient returns a GitHub client authenticated with token whose
+// requests are rate limited and retried according to opts.
+func NewGitHubClient(token string, opts ClientOptions) (*github.Client, error) {
+	return NewGitHubClientWithTokens([]string{token}, opts)
+}
+
+// NewGitHubClientWithTokens returns a GitHub client that spreads its requests
+// across several tokens. Each request uses the token with the most rate limit
+// budget left, and a request rejected by the rate limit is resent with the
+// next token before falling back to waiting for the limit to reset.
+func NewGitHubClientWithTokens(tokens []string, opts ClientOptions) (*github.Client, error) {
+	if len(tokens) == 0 {
+		return nil, errors.New("GitHub token is empty")
+	}
+
+	// The tokens are added below the ETag store rather than above it as on
+	// other platforms, so it sees which token each request carries.
+	base := opts.baseTransport()
+	if opts.ETagDir != "" {
+		base = &etagTransport{transport: base, dir: opts.ETagDir, logf: opts.logf}
+		opts.ETagDir = ""
+	}
+
+	pool := &githubTokenPool{logf: opts.logf}
+	for _, token := range tokens {
+		if token == "" {
+			return nil, errors.New("GitHub token is empty")
+		}
+		ts := oauth2.StaticTokenSource(
+			&oauth2.Token{AccessToken: token},
+		)
+		pool.tokens = append(pool.tokens, &githubToken{
+			transport: &oauth2.Transport{Source: ts, Base: base},
+			remaining: map[string]int{},
+			reset:     map[string]time.Time{},
+		})
+	}
+
+	// Each token has its own search budget, so the pacing scales with them.
+	paced := &githubSearchTransport{
+		transport: pool,
+		search:    rate.NewLimiter(githubSearchRate*rate.Limit(len(tokens)), 1),
+		code:      rate.NewLimiter(githubCodeSearchRate*rate.Limit(len(tokens)), 1),
+	}
+	tc := &http.Client{
+		Transport: newTransport(paced, nil, opts),
+	}
+
+	return github.NewClient(tc), nil
+}
+
+// githubSearchTransport paces requests by the search rate limit resource they
+// count against, and sends other requests straight away.
+type githubSearchTransport struct {
+	transport http.RoundTripper
+	search    *rate.Limiter
+	code      *rate.Limiter
+}
+
+func (t *githubSearchTransport) RoundTrip(req *http.Request) (*http.Response, error) {
+	limiter := t.search
+	switch githubResource(req) {
+	case "core":
+		return t.transport.RoundTrip(req)
+	case "code_search":
+		limiter = t.code
+	}
+	if err := limiter.Wait(req.Context()); err != nil {
+		return nil, err
+	}
+	return t.transport.RoundTrip(req)
+}
+
+// githubToken is a transport authenticated with one token, and the rate limit
+// budget GitHub last reported for it per rate limit resource.
+type githubToken struct {
+	transport http.RoundTripper
+	remaining map[string]int
+	reset     map[string]time.Time
+}
+
+// githubTokenPool sends each request with the token that has the most budget
+// left for its rate limit resource, moving on to another token when one is
+// rate limited.
+type githubTokenPool struct {
+	mu     sync.Mutex
+	tokens []*githubToken
+	logf   func(format string, a ...interface{})
+}
+
+// githubResource returns the rate limit resource a request counts against,
+// named as in GitHub's X-RateLimit-Resource header. Searches have their own,
+// smaller budget, and code search a smaller one still.
+func githubResource(req *http.Request) string {
+	switch {
+	case strings.Contains(req.URL.Path, "/search/code"):
+		return "code_search"
+	case strings.Contains(req.URL.Path, "/search/"):
+		return "search"
+	}
+	return "core"
+}
+
+// pick returns the index of the untried token with the most budget left for
+// resource, or -1 if every token has been tried. Tokens without a known
+// budget, or whose budget has since reset, are preferred.
+func (p *githubTokenPool) pick(resource string, tried map[int]bool) int {
+	p.mu.Lock()
+	defer p.mu.Unlock()
+
+	best, bestRemaining := -1, -1
+	for i, token := range p.tokens {
+		if tried[i] {
+			continue
+		}
+		remaining, ok := token.remaining[resource]
+		if !ok || time.Now().After(token.reset[resource]) {
+			remaining = math.MaxInt32
+		}
+		if remaining > bestRemaining {
+			best, bestRemaining = i, remaining
+		}
+	}
+	return best
+}
+
+// update records the budget GitHub reported for a token in resp.
+func (p *githubTokenPool) update(i int, resource string, resp *http.Response) {
+	remaining, err := strconv.Atoi(resp.Header.Get("X-RateLimit-Remaining"))
+	if err != nil {
+		return
+	}
+	if r := resp.Header.Get("X-RateLimit-Resource"); r != "" {
+		resource = r
+	}
+	reset, _ := strconv.ParseInt(resp.Header.Get("X-RateLimit-Reset"), 10, 64)
+
+	p.mu.Lock()
+	defer p.mu.Unlock()
+	p.tokens[i].remaining[resource] = remaining
+	p.tokens[i].reset[resource] = time.Unix(reset, 0)
+}
+
+func (p *githubTokenPool) RoundTrip(req *http.Request) (*http.Response, error) {
+	resource := githubResource(req)
+	tried := map[int]bool{}
+	for {
+		i := p.pick(resource, tried)
+		tried[i] = true
+
+		resp, err := p.tokens[i].transport.RoundTrip(req)
+		if err != nil {
+			return nil, err
+		}
+		p.update(i, resource, resp)
+
+		if _, limited := retryDelay(resp, 0); !limited || len(tried) == len(p.tokens) {
+			return resp, nil
+		}
+
+		if req.Body != nil {
+			if req.GetBody == nil {
+				return resp, nil
+			}
+			if req.Body, err = req.GetBody(); err != nil {
+				return resp, nil
+			}
+		}
+
+		io.Copy(io.Discard, resp.Body)
+		resp.Body.Close()
+
+		p.logf("GitHub token %d of %d is rate limited, switching tokens\n", i+1, len(p.tokens))
+	}
+}
+
+// SearchGitHubOrganizations returns up to maxResults organizations matching
+// query.
+func (s *Searcher) SearchGitHubOrganizations(ctx context.Context, query string, maxResults int) ([]Result, error) {
+	return s.searchGitHubAccounts(ctx, CategoryOrg, query, maxResults)
+}
+
+// SearchGitHubRepositories returns up to maxResults repositories matching
+// query.
+func (s *Searcher) SearchGitHubRepositories(ctx context.Context, query string, maxResults int) ([]Result, error) {
+	var repos []Result
+	search := RepoQuery(s.Org, s.User, query, s.MinStars)
+
+	opt := &github.SearchOptions{ListOptions: github.ListOptions{PerPage: perPage(maxResults, maxPerPage)}}
+	for {
+		reqCtx, cancel := s.requestContext(ctx)
+		results, resp, err := s.GitHub.Search.Repositories(reqCtx, search, opt)
+		cancel()
+		s.logGitHubRate(resp)
+		if err != nil {
+			return nil, err
+		}
+
+		from := len(repos)
+		for _, repo := range results.Repositories {
+			r := githubRepositoryResult(repo)
+			r.Platform, r.Category, r.Query = PlatformGitHub, CategoryRepo, query
+			repos = append(repos, r)
+		}
+		emitPage(ctx, repos, from, maxResults)
+
+		if len(repos) >= maxResults || resp.NextPage == 0 {
+			break
+		}
+		opt.Page = resp.NextPage
+	}
+
+	return truncate(repos, maxResults), nil
+}
+
+// ListGitHubOrgRepositories returns up to maxResults repositories owned by
+// the organization org, most recently pushed first. Each result's Query is
+// org.
+func (s *Searcher) ListGitHubOrgRepositories(ctx context.Context, org string, maxResults int) ([]Result, error) {
+	var repos []Result
+	opt := &github.RepositoryListByOrgOptions{Sort: "pushed", ListOptions: github.ListOptions{PerPage: perPage(maxResults, maxPerPage)}}
+	for {
+		reqCtx, cancel := s.requestContext(ctx)
+		results, resp, err := s.GitHub.Repositories.ListByOrg(reqCtx, org, opt)
+		cancel()
+		s.logGitHubRate(resp)
+		if err != nil {
+			return nil, err
+		}
+
+		from := len(repos)
+		for _, repo := range results {
+			r := githubRepositoryResult(repo)
+			r.Platform, r.Category, r.Query = PlatformGitHub, CategoryRepo, org
+			repos = append(repos, r)
+		}
+		emitPage(ctx, repos, from, maxResults)
+
+		if len(repos) >= maxResults || resp.NextPage == 0 {
+			break
+		}
+		opt.Page = resp.NextPage
+	}
+
+	return truncate(repos, maxResults), nil
+}
+
+// githubRepositoryResult converts repo to a Result without its platform,
+// category, or query.
+func githubRepositoryResult(repo *github.Repository) Result {
+	return Result{Name: repo.GetFullName(), URL: repo.GetHTMLURL(), Description: repo.GetDescription(), Stars: repo.GetStargazersCount(),
+		LastActive: repo.GetPushedAt().Time, Fork: repo.GetFork(), Archived: repo.GetArchived(), Language: repo.GetLanguage()}
+}
+
+// SearchGitHubCode returns up to maxResults files whose contents match query.
+// Each result is named "owner/repo:path".
+func (s *Searcher) SearchGitHubCode(ctx context.Context, query string, maxResults int) ([]Result, error) {
+	var files []Result
+	opt := &github.SearchOptions{ListOptions: github.ListOptions{PerPage: perPage(maxResults, maxPerPage)}}
+	for {
+		reqCtx, cancel := s.requestContext(ctx)
+		results, resp, err := s.GitHub.Search.Code(reqCtx, query, opt)
+		cancel()
+		s.logGitHubRate(resp)
+		if err != nil {
+			return nil, err
+		}
+
+		from := len(files)
+		for _, code := range results.CodeResults {
+			name := code.GetRepository().GetFullName() + ":" + code.GetPath()
+			files = append(files, Result{Platform: PlatformGitHub, Category: CategoryCode, Query: query, Name: name, URL: code.GetHTMLURL()})
+		}
+		emitPage(ctx, files, from, maxResults)
+
+		if len(files) >= maxResults || resp.NextPage == 0 {
+			break
+		}
+		opt.Page = resp.NextPage
+	}
+
+	return truncate(files, maxResults), nil
+}
+
+// ListGitHubGists returns up to maxResults public gists owned by the user
+// named username. GitHub has no gist search API, so this enumerates a
+// candidate user's gists rather than searching their contents. Each result is
+// named by its URL and described by the gist's description.
+func (s *Searcher) ListGitHubGists(ctx context.Context, username string, maxResults int) ([]Result, error) {
+	var gists []Result
+	opt := &github.GistListOptions{ListOptions: github.ListOptions{PerPage: perPage(maxResults, maxPerPage)}}
+	for {
+		reqCtx, cancel := s.requestContext(ctx)
+		page, resp, err := s.GitHub.Gists.List(reqCtx, username, opt)
+		cancel()
+		s.logGitHubRate(resp)
+		if err != nil {
+			if resp != nil && isNotFound(resp.Response) {
+				return nil, nil
+			}
+			return nil, err
+		}
+
+		from := len(gists)
+		for _, gist := range page {
+			gists = append(gists, Result{Platform: PlatformGitHub, Category: CategoryGist, Query: username, Name: gist.GetHTMLURL(), URL: gist.GetHTMLURL(),
+				Description: gist.GetDescription(), LastActive: gist.GetUpdatedAt()})
+		}
+		emitPage(ctx, gists, from, maxResults)
+
+		if len(gists) >= maxResults || resp.NextPage == 0 {
+			break
+		}
+		opt.Page = resp.NextPage
+	}
+
+	return truncate(gists, maxResults), nil
+}
+
+// SearchGitHubTopics returns up to maxResults topics matching query.
+func (s *Searcher) SearchGitHubTopics(ctx context.Context, query string, maxResults int) ([]Result, error) {
+	var topics []Result
+	opt := &github.SearchOptions{ListOptions: github.ListOptions{PerPage: perPage(maxResults, maxPerPage)}}
+	for {
+		reqCtx, cancel := s.requestContext(ctx)
+		results, resp, err := s.GitHub.Search.Topics(reqCtx, query, opt)
+		cancel()
+		s.logGitHubRate(resp)
+		if err != nil {
+			return nil, err
+		}
+
+		from := len(topics)
+		for _, topic := range results.Topics {
+			topics = append(topics, Result{Platform: PlatformGitHub, Category: CategoryTopic, Query: query, Name: topic.GetName(),
+				URL: githubTopicURL + topic.GetName(), Description: topic.GetShortDescription()})
+		}
+		emitPage(ctx, topics, from, maxResults)
+
+		if len(topics) >= maxResults || resp.NextPage == 0 {
+			break
+		}
+		opt.Page = resp.NextPage
+	}
+
+	return truncate(topics, maxResults), nil
+}
+
+// SearchGitHubUsers returns up to maxResults users matching query.
+func (s *Searcher) SearchGitHubUsers(ctx context.Context, query string, maxResults int) ([]Result, error) {
+	return s.searchGitHubAccounts(ctx, CategoryUser, query, maxResults)
+}
+
+// githubAccounts is the part of a combined account search kept for the
+// category searched second. complete reports whether the search ran out of
+// pages, so results holds every match rather than a prefix of them.
+type githubAccounts struct {
+	results  []Result
+	complete bool
+}
+
+// searchGitHubAccounts uses the user search endpoint, which returns both users
+// and organizations, narrowed by a type qualifier.
+func (s *Searcher) searchGitHubAccounts(ctx context.Context, category, query string, maxResults int) ([]Result, error) {
+	if s.CombineGitHubAccounts {
+		return s.searchGitHubAccountsCombined(ctx, category, query, maxResults)
+	}
+	return s.searchGitHubAccountsTyped(ctx, category, query, maxResults)
+}
+
+// searchGitHubAccountsTyped searches for accounts of category alone.
+func (s *Searcher) searchGitHubAccountsTyped(ctx context.Context, category, query string, maxResults int) ([]Result, error) {
+	var accounts []Result
+	opt := &github.SearchOptions{ListOptions: github.ListOptions{PerPage: perPage(maxResults, maxPerPage)}}
+	for {
+		reqCtx, cancel := s.requestContext(ctx)
+		results, resp, err := s.GitHub.Search.Users(reqCtx, SearchQuery(PlatformGitHub, category, query), opt)
+		cancel()
+		s.logGitHubRate(resp)
+		if err != nil {
+			return nil, err
+		}
+
+		from := len(accounts)
+		for _, user := range results.Users {
+			accounts = append(accounts, Result{Platform: PlatformGitHub, Category: category, Query: query, Name: user.GetLogin(), URL: user.GetHTMLURL()})
+		}
+		emitPage(ctx, accounts, from, maxResults)
+
+		if len(accounts) >= maxResults || resp.NextPage == 0 {
+			break
+		}
+		opt.Page = resp.NextPage
+	}
+
+	return truncate(accounts, maxResults), nil
+}
+
+// searchGitHubAccountsCombined answers an organization or user search from
+// the other half of an earlier combined search for query. Without one, it
+// searches without a type qualifier, returning the accounts of category and
+// keeping the rest for the other category. Whenever that leaves category
+// short of maxResults while more accounts matched, accounts of one type
+// having crowded out the other, it falls back to a typed search.
+func (s *Searcher) searchGitHubAccountsCombined(ctx context.Context, category, query string, maxResults int) ([]Result, error) {
+	key := category + "\x00" + query
+	s.accountsMu.Lock()
+	kept, ok := s.accounts[key]
+	delete(s.accounts, key)
+	s.accountsMu.Unlock()
+
+	if ok {
+		if !kept.complete && len(kept.results) < maxResults {
+			return s.searchGitHubAccountsTyped(ctx, category, query, maxResults)
+		}
+		results := truncate(kept.results, maxResults)
+		emitPage(ctx, results, 0, maxResults)
+		return results, nil
+	}
+
+	other := CategoryUser
+	if category == CategoryUser {
+		other = CategoryOrg
+	}
+
+	// Two separate searches would fetch up to maxResults each, so stop
+	// after as many results in total to never send more requests than they
+	// would.
+	var accounts, rest []Result
+	var complete bool
+	fetched := 0
+	opt := &github.SearchOptions{ListOptions: github.ListOptions{PerPage: perPage(2*maxResults, maxPerPage)}}
+	for {
+		reqCtx, cancel := s.requestContext(ctx)
+		results, resp, err := s.GitHub.Search.Users(reqCtx, query, opt)
+		cancel()
+		s.logGitHubRate(resp)
+		if err != nil {
+			return nil, err
+		}
+
+		for _, user := range results.Users {
+			fetched++
+			resultCategory := CategoryUser
+			if user.GetType() == "Organization" {
+				resultCategory = CategoryOrg
+			}
+			result := Result{Platform: PlatformGitHub, Category: resultCategory, Query: query, Name: user.GetLogin(), URL: user.GetHTMLURL()}
+			if resultCategory == category {
+				accounts = append(accounts, result)
+			} else {
+				rest = append(rest, result)
+			}
+		}
+
+		if resp.NextPage == 0 {
+			complete = true
+			break
+		}
+		if fetched >= 2*maxResults {
+			break
+		}
+		opt.Page = resp.NextPage
+	}
+
+	// Pages are held back until here, as a fallback search would pass
+	// them on again.
+	var err error
+	if !complete && len(accounts) < maxResults {
+		accounts, err = s.searchGitHubAccountsTyped(ctx, category, query, maxResults)
+	} else {
+		emitPage(ctx, accounts, 0, maxResults)
+	}
+	s.keepGitHubAccounts(ctx, other, query, githubAccounts{results: rest, complete: complete})
+
+	return truncate(accounts, maxResults), err
+}
+
+// keepGitHubAccounts keeps accounts for the search of category for query to
+// use. A cancelled search, as by -first, ends the word's searches, so nothing
+// would use them.
+func (s *Searcher) keepGitHubAccounts(ctx context.Context, category, query string, accounts githubAccounts) {
+	if ctx.Err() != nil {
+		return
+	}
+
+	s.accountsMu.Lock()
+	defer s.accountsMu.Unlock()
+	if s.accounts == nil {
+		s.accounts = make(map[string]githubAccounts)
+	}
+	s.accounts[category+"\x00"+query] = accounts
+}
+
+func (s *Searcher) authGitHub(ctx context.Context) error {
+	_, resp, err := s.GitHub.Users.Get(ctx, "")
+	s.logGitHubRate(resp)
+	if resp != nil {
+		return authError(resp.Response, err)
+	}
+	return err
+}
+
+func (s *Searcher) checkGitHub(ctx context.Context, category, name string) ([]Result, error) {
+	reqCtx, cancel := s.requestContext(ctx)
+	defer cancel()
+
+	var found Result
+	var resp *github.Response
+	var err error
+	switch category {
+	case CategoryOrg:
+		var org *github.Organization
+		if org, resp, err = s.GitHub.Organizations.Get(reqCtx, name); err == nil {
+			found = Result{Name: org.GetLogin(), URL: org.GetHTMLURL(), Description: org.GetDescription(), LastActive: org.GetUpdatedAt()}
+		}
+	case CategoryRepo:
+		owner, repoName := splitRepoPath(name)
+		var repo *github.Repository
+		if repo, resp, err = s.GitHub.Repositories.Get(reqCtx, owner, repoName); err == nil {
+			found = githubRepositoryResult(repo)
+		}
+	case CategoryUser:
+		var user *github.User
+		if user, resp, err = s.GitHub.Users.Get(reqCtx, name); err == nil {
+			// Users.Get also resolves organizations.
+			if user.GetType() != "User" {
+				return nil, nil
+			}
+			found = githubUserResult(user)
+		}
+	default:
+		return nil, ErrUnsupported
+	}
+	s.logGitHubRate(resp)
+
+	if err != nil {
+		if resp != nil && isNotFound(resp.Response) {
+			return nil, nil
+		}
+		return nil, err
+	}
+
+	found.Platform, found.Category, found.Query = PlatformGitHub, category, name
+	return []Result{found}, nil
+}
+
+func (s *Searcher) resolveGitHubUser(ctx context.Context, r Result) (Result, error) {
+	reqCtx, cancel := s.requestContext(ctx)
+	defer cancel()
+
+	user, resp, err := s.GitHub.Users.Get(reqCtx, r.Name)
+	s.logGitHubRate(resp)
+	if err != nil {
+		return r, err
+	}
+
+	resolved := githubUserResult(user)
+	resolved.Platform, resolved.Category, resolved.Query, resolved.Score = r.Platform, r.Category, r.Query, r.Score
+	return resolved, nil
+}
+
+// githubUserResult converts a user's full profile to a Result without its
+// platform, category, or query.
+func githubUserResult(user *github.User) Result {
+	return Result{Name: user.GetLogin(), URL: user.GetHTMLURL(), Description: user.GetBio(), LastActive: user.GetUpdatedAt().Time, DisplayName: user.GetName(),
+		Company: user.GetCompany(), Blog: user.GetBlog(), Email: user.GetEmail(), PublicRepos: user.GetPublicRepos()}
+}
+
+// logGitHubRate reports the rate limit budget GitHub returned with resp to
+// Logf and RateFunc.
+func (s *Searcher) logGitHubRate(resp *github.Response) {
+	if resp == nil || resp.Rate.Limit == 0 {
+		return
+	}
+	if s.RateFunc != nil {
+		s.RateFunc(PlatformGitHub, resp.Rate.Remaining, resp.Rate.Limit)
+	}
+	if s.Logf == nil {
+		return
+	}
+	s.Logf("GitHub rate limit: %d/%d remaining, resets at %s\n", resp.Rate.Remaining, resp.Rate.Limit, resp.Rate.Reset.Format("15:04:05"))
+}
@@ -0,0 +1,295 @@
+package dorky
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"net/http"
+	"net/http/httptest"
+	"net/url"
+	"testing"
+	"time"
+
+	"github.com/google/go-github/v38/github"
+	"golang.org/x/time/rate"
+)
+
+// newGitHubTestSearcher returns a Searcher whose GitHub client sends every
+// request to handler.
+func newGitHubTestSearcher(t *testing.T, handler http.HandlerFunc) *Searcher {
+	t.Helper()
+	server := httptest.NewServer(handler)
+	t.Cleanup(server.Close)
+
+	client, err := NewGitHubClient("token", ClientOptions{Transport: server.Client().Transport})
+	if err != nil {
+		t.Fatal(err)
+	}
+	client.BaseURL, _ = url.Parse(server.URL + "/")
+	return &Searcher{GitHub: client}
+}
+
+func TestSearchQuery(t *testing.T) {
+	tests := []struct {
+		platform, category, query, want string
+	}{
+		{PlatformGitHub, CategoryOrg, "acme", "type:org acme"},
+		{PlatformGitHub, CategoryUser, "acme", "type:user acme"},
+		{PlatformGitHub, CategoryRepo, "acme", "acme"},
+		{PlatformGitLab, CategoryOrg, "acme", "acme"},
+		{PlatformBitbucket, CategoryRepo, `a"b`, `name ~ "a\"b"`},
+	}
+	for _, tt := range tests {
+		if got := SearchQuery(tt.platform, tt.category, tt.query); got != tt.want {
+			t.Errorf("SearchQuery(%q, %q, %q) = %q, want %q", tt.platform, tt.category, tt.query, got, tt.want)
+		}
+	}
+}
+
+func TestSearchGitHubOrganizations(t *testing.T) {
+	var gotQuery string
+	s := newGitHubTestSearcher(t, func(w http.ResponseWriter, r *http.Request) {
+		if r.URL.Path != "/search/users" {
+			t.Errorf("request to %s, want /search/users", r.URL.Path)
+		}
+		gotQuery = r.URL.Query().Get("q")
+		fmt.Fprint(w, `{"total_count": 2, "items": [
+			{"login": "acme", "html_url": "https://github.com/acme", "type": "Organization"},
+			{"login": "acme-labs", "html_url": "https://github.com/acme-labs", "type": "Organization"}
+		]}`)
+	})
+
+	results, err := s.Search(context.Background(), PlatformGitHub, CategoryOrg, "acme", 10)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if gotQuery != "type:org acme" {
+		t.Errorf("query = %q, want %q", gotQuery, "type:org acme")
+	}
+
+	want := []Result{
+		{Platform: PlatformGitHub, Category: CategoryOrg, Query: "acme", Name: "acme", URL: "https://github.com/acme"},
+		{Platform: PlatformGitHub, Category: CategoryOrg, Query: "acme", Name: "acme-labs", URL: "https://github.com/acme-labs"},
+	}
+	if len(results) != len(want) {
+		t.Fatalf("got %d results, want %d", len(results), len(want))
+	}
+	for i := range want {
+		if results[i] != want[i] {
+			t.Errorf("result %d = %+v, want %+v", i, results[i], want[i])
+		}
+	}
+}
+
+func TestSearchGitHubRepositories(t *testing.T) {
+	var gotQuery string
+	s := newGitHubTestSearcher(t, func(w http.ResponseWriter, r *http.Request) {
+		gotQuery = r.URL.Query().Get("q")
+		fmt.Fprint(w, `{"total_count": 1, "items": [{
+			"full_name": "acme/api",
+			"html_url": "https://github.com/acme/api",
+			"description": "The Acme API",
+			"stargazers_count": 42,
+			"pushed_at": "2021-03-04T05:06:07Z",
+			"fork": true,
+			"archived": true,
+			"language": "Go"
+		}]}`)
+	})
+	s.Org, s.MinStars = "acme", 10
+
+	results, err := s.Search(context.Background(), PlatformGitHub, CategoryRepo, "api", 10)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if want := "org:acme api stars:>=10"; gotQuery != want {
+		t.Errorf("query = %q, want %q", gotQuery, want)
+	}
+	if len(results) != 1 {
+		t.Fatalf("got %d results, want 1", len(results))
+	}
+
+	r := results[0]
+	if r.Platform != PlatformGitHub || r.Category != CategoryRepo || r.Query != "api" {
+		t.Errorf("result is %s/%s for %q, want github/repo for \"api\"", r.Platform, r.Category, r.Query)
+	}
+	if r.Name != "acme/api" || r.URL != "https://github.com/acme/api" || r.Description != "The Acme API" {
+		t.Errorf("result = %+v, want acme/api with its URL and description", r)
+	}
+	if r.Stars != 42 || !r.Fork || !r.Archived || r.Language != "Go" {
+		t.Errorf("result = %+v, want 42 stars, forked, archived, and Go", r)
+	}
+	if got := r.LastActive.UTC().Format("2006-01-02T15:04:05Z"); got != "2021-03-04T05:06:07Z" {
+		t.Errorf("LastActive = %s, want 2021-03-04T05:06:07Z", got)
+	}
+}
+
+func TestSearchGitHubErrors(t *testing.T) {
+	tests := []struct {
+		name   string
+		status int
+	}{
+		{"unauthorized", http.StatusUnauthorized},
+		{"invalid query", http.StatusUnprocessableEntity},
+		{"server error", http.StatusInternalServerError},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			s := newGitHubTestSearcher(t, func(w http.ResponseWriter, r *http.Request) {
+				w.WriteHeader(tt.status)
+				fmt.Fprint(w, `{"message": "failed"}`)
+			})
+
+			results, err := s.Search(context.Background(), PlatformGitHub, CategoryRepo, "acme", 10)
+			if err == nil {
+				t.Fatalf("got %d results and no error, want an error", len(results))
+			}
+		})
+	}
+}
+
+func TestAuthenticateGitHub(t *testing.T) {
+	s := newGitHubTestSearcher(t, func(w http.ResponseWriter, r *http.Request) {
+		w.WriteHeader(http.StatusUnauthorized)
+		fmt.Fprint(w, `{"message": "Bad credentials"}`)
+	})
+
+	if err := s.Authenticate(context.Background(), PlatformGitHub); !errors.Is(err, ErrAuth) {
+		t.Errorf("Authenticate = %v, want ErrAuth", err)
+	}
+}
+
+func TestCheckGitHubNotFound(t *testing.T) {
+	s := newGitHubTestSearcher(t, func(w http.ResponseWriter, r *http.Request) {
+		w.WriteHeader(http.StatusNotFound)
+		fmt.Fprint(w, `{"message": "Not Found"}`)
+	})
+
+	results, err := s.Check(context.Background(), PlatformGitHub, CategoryOrg, "acme")
+	if err != nil || len(results) != 0 {
+		t.Errorf("Check = %v, %v, want no results and no error", results, err)
+	}
+}
+
+func TestGitHubSearchTransport(t *testing.T) {
+	ok := roundTripFunc(func(req *http.Request) (*http.Response, error) {
+		return &http.Response{StatusCode: http.StatusOK, Body: http.NoBody}, nil
+	})
+	transport := &githubSearchTransport{
+		transport: ok,
+		search:    rate.NewLimiter(rate.Every(time.Hour), 1),
+		code:      rate.NewLimiter(rate.Every(time.Hour), 1),
+	}
+
+	// Each limiter allows one request straight away and then none for an
+	// hour, so a request that is paced fails once ctx expires.
+	tests := []struct {
+		path   string
+		paced  bool
+		reason string
+	}{
+		{"/search/repositories", false, "first search"},
+		{"/search/code", false, "first code search, with its own budget"},
+		{"/users/acme", false, "core request"},
+		{"/orgs/acme/repos", false, "another core request"},
+		{"/search/users", true, "second search"},
+		{"/search/code", true, "second code search"},
+	}
+	for _, tt := range tests {
+		ctx, cancel := context.WithTimeout(context.Background(), 50*time.Millisecond)
+		req, _ := http.NewRequestWithContext(ctx, http.MethodGet, "https://api.github.com"+tt.path, nil)
+		_, err := transport.RoundTrip(req)
+		cancel()
+		if paced := err != nil; paced != tt.paced {
+			t.Errorf("%s (%s): paced = %v, want %v", tt.path, tt.reason, paced, tt.paced)
+		}
+	}
+}
+
+// newUnpacedGitHubTestSearcher is like newGitHubTestSearcher, without the
+// search rate limiting that would slow down tests sending several searches.
+func newUnpacedGitHubTestSearcher(t *testing.T, handler http.HandlerFunc) *Searcher {
+	t.Helper()
+	server := httptest.NewServer(handler)
+	t.Cleanup(server.Close)
+
+	client := github.NewClient(server.Client())
+	client.BaseURL, _ = url.Parse(server.URL + "/")
+	return &Searcher{GitHub: client, CombineGitHubAccounts: true}
+}
+
+func TestSearchGitHubAccountsCombinedFallsBack(t *testing.T) {
+	var queries []string
+	s := newUnpacedGitHubTestSearcher(t, func(w http.ResponseWriter, r *http.Request) {
+		q := r.URL.Query().Get("q")
+		queries = append(queries, q)
+		if q == "type:org acme" {
+			fmt.Fprint(w, `{"items": [{"login": "acme", "type": "Organization"}]}`)
+			return
+		}
+		// Users fill the untyped search, and more pages follow.
+		w.Header().Set("Link", `<`+r.URL.Path+`?q=acme&page=2>; rel="next"`)
+		fmt.Fprint(w, `{"items": [{"login": "acme-dev", "type": "User"}, {"login": "acme-ops", "type": "User"}]}`)
+	})
+
+	orgs, err := s.Search(context.Background(), PlatformGitHub, CategoryOrg, "acme", 1)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if len(orgs) != 1 || orgs[0].Name != "acme" {
+		t.Errorf("orgs = %+v, want acme", orgs)
+	}
+
+	users, err := s.Search(context.Background(), PlatformGitHub, CategoryUser, "acme", 1)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if len(users) != 1 || users[0].Name != "acme-dev" {
+		t.Errorf("users = %+v, want acme-dev", users)
+	}
+
+	// The user search is answered from the untyped search.
+	want := []string{"acme", "type:org acme"}
+	if fmt.Sprint(queries) != fmt.Sprint(want) {
+		t.Errorf("queries = %q, want %q", queries, want)
+	}
+}
+
+func TestSearchGitHubAccountsCombinedCancelled(t *testing.T) {
+	s := newUnpacedGitHubTestSearcher(t, func(w http.ResponseWriter, r *http.Request) {
+		fmt.Fprint(w, `{"items": [{"login": "acme", "type": "Organization"}, {"login": "acme-dev", "type": "User"}]}`)
+	})
+
+	// Cancelling from the page callback, as -first does, leaves nothing
+	// behind for a user search that will not run.
+	ctx, cancel := context.WithCancel(context.Background())
+	defer cancel()
+	_, err := s.SearchStream(ctx, PlatformGitHub, CategoryOrg, "acme", 10, func([]Result) { cancel() })
+	if err != nil {
+		t.Fatal(err)
+	}
+	if len(s.accounts) != 0 {
+		t.Errorf("kept %d account searches after cancellation, want none", len(s.accounts))
+	}
+}
+
+func TestGitHubResultsWithNilFields(t *testing.T) {
+	if got := githubRepositoryResult(&github.Repository{}); got != (Result{}) {
+		t.Errorf("githubRepositoryResult of an empty repository = %+v, want an empty Result", got)
+	}
+	if got := githubUserResult(&github.User{}); got != (Result{}) {
+		t.Errorf("githubUserResult of an empty user = %+v, want an empty Result", got)
+	}
+
+	s := newGitHubTestSearcher(t, func(w http.ResponseWriter, r *http.Request) {
+		fmt.Fprint(w, `{"items": [{"full_name": "acme/api", "html_url": null, "description": null, "stargazers_count": null, "pushed_at": null}]}`)
+	})
+	results, err := s.Search(context.Background(), PlatformGitHub, CategoryRepo, "api", 10)
+	if err != nil {
+		t.Fatal(err)
+	}
+	want := Result{Platform: PlatformGitHub, Category: CategoryRepo, Query: "api", Name: "acme/api"}
+	if len(results) != 1 || results[0] != want {
+		t.Errorf("results = %+v, want [%+v]", results, want)
+	}
+}
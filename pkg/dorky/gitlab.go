@@ -0,0 +1,263 @@
+package dorky
+
+import (
+	"context"
+	"errors"
+	"net/http"
+	"time"
+
+	"github.com/xanzy/go-gitlab"
+)
+
+// ErrSnippetSearchDenied is returned when the GitLab instance refuses snippet
+// search, which some instances restrict to administrators.
+var ErrSnippetSearchDenied = errors.New("GitLab snippet search is not available to this token; it may require admin access")
+
+// NewGitLabClient returns a GitLab client authenticated with token. baseURL
+// selects a self-hosted instance and may be empty to use gitlab.com.
+func NewGitLabClient(token, baseURL string, opts ClientOptions) (*gitlab.Client, error) {
+	if token == "" {
+		return nil, errors.New("GitLab token is empty")
+	}
+
+	// go-gitlab retries on its own with a fixed budget, so disable that in
+	// favour of retryTransport which honours opts.Retries. Self-hosted
+	// instances under load fail with intermittent 5xx responses, so those
+	// are retried too.
+	transport := newTransport(opts.baseTransport(), nil, opts)
+	transport.serverErrors = true
+	hc := &http.Client{Transport: transport}
+
+	options := []gitlab.ClientOptionFunc{gitlab.WithHTTPClient(hc), gitlab.WithoutRetries()}
+	if baseURL != "" {
+		options = append(options, gitlab.WithBaseURL(baseURL))
+	}
+
+	return gitlab.NewClient(token, options...)
+}
+
+// SearchGitLabGroups returns up to maxResults groups matching query, named by
+// their full path. Subgroups at any depth are searched as well as top-level
+// groups, so a word that only matches a nested subgroup still finds it.
+func (s *Searcher) SearchGitLabGroups(ctx context.Context, query string, maxResults int) ([]Result, error) {
+	var groupResults []Result
+	// Without AllAvailable, non-admin tokens only see groups they belong to.
+	opt := &gitlab.ListGroupsOptions{
+		Search:       gitlab.String(query),
+		AllAvailable: gitlab.Bool(true),
+		TopLevelOnly: gitlab.Bool(false),
+		ListOptions:  gitlab.ListOptions{PerPage: perPage(maxResults, maxPerPage)},
+	}
+	for {
+		reqCtx, cancel := s.requestContext(ctx)
+		groups, resp, err := s.GitLab.Groups.ListGroups(opt, gitlab.WithContext(reqCtx))
+		cancel()
+		if err != nil {
+			return nil, err
+		}
+
+		from := len(groupResults)
+		for _, group := range groups {
+			groupResults = append(groupResults, Result{Platform: PlatformGitLab, Category: CategoryOrg, Query: query, Name: group.FullPath, URL: group.WebURL, Description: group.Description})
+		}
+		emitPage(ctx, groupResults, from, maxResults)
+
+		if len(groupResults) >= maxResults || resp.NextPage == 0 {
+			break
+		}
+		opt.Page = resp.NextPage
+	}
+
+	return truncate(groupResults, maxResults), nil
+}
+
+// SearchGitLabUsers returns up to maxResults users matching query.
+func (s *Searcher) SearchGitLabUsers(ctx context.Context, query string, maxResults int) ([]Result, error) {
+	var userResults []Result
+	opt := &gitlab.ListUsersOptions{Search: gitlab.String(query), ListOptions: gitlab.ListOptions{PerPage: perPage(maxResults, maxPerPage)}}
+	for {
+		reqCtx, cancel := s.requestContext(ctx)
+		users, resp, err := s.GitLab.Users.ListUsers(opt, gitlab.WithContext(reqCtx))
+		cancel()
+		if err != nil {
+			return nil, err
+		}
+
+		from := len(userResults)
+		for _, user := range users {
+			userResults = append(userResults, Result{Platform: PlatformGitLab, Category: CategoryUser, Query: query, Name: user.Username, URL: user.WebURL, Description: user.Bio, LastActive: isoTime(user.LastActivityOn),
+				DisplayName: user.Name})
+		}
+		emitPage(ctx, userResults, from, maxResults)
+
+		if len(userResults) >= maxResults || resp.NextPage == 0 {
+			break
+		}
+		opt.Page = resp.NextPage
+	}
+
+	return truncate(userResults, maxResults), nil
+}
+
+// SearchGitLabProjects returns up to maxResults projects matching query. If
+// s.Org is set only projects in that group and its subgroups are searched,
+// and if s.User is set only that user's projects.
+func (s *Searcher) SearchGitLabProjects(ctx context.Context, query string, maxResults int) ([]Result, error) {
+	return s.listGitLabProjects(ctx, CategoryRepo, query, maxResults, func(reqCtx context.Context, listOpt gitlab.ListOptions) ([]*gitlab.Project, *gitlab.Response, error) {
+		if s.Org != "" {
+			opt := &gitlab.ListGroupProjectsOptions{Search: gitlab.String(query), IncludeSubgroups: gitlab.Bool(true), ListOptions: listOpt}
+			return s.GitLab.Groups.ListGroupProjects(s.Org, opt, gitlab.WithContext(reqCtx))
+		}
+		opt := &gitlab.ListProjectsOptions{Search: gitlab.String(query), ListOptions: listOpt}
+		if s.User != "" {
+			return s.GitLab.Projects.ListUserProjects(s.User, opt, gitlab.WithContext(reqCtx))
+		}
+		return s.GitLab.Projects.ListProjects(opt, gitlab.WithContext(reqCtx))
+	})
+}
+
+// SearchGitLabTopics returns up to maxResults projects tagged with the topic
+// query, named by their full path.
+func (s *Searcher) SearchGitLabTopics(ctx context.Context, query string, maxResults int) ([]Result, error) {
+	return s.listGitLabProjects(ctx, CategoryTopic, query, maxResults, func(reqCtx context.Context, listOpt gitlab.ListOptions) ([]*gitlab.Project, *gitlab.Response, error) {
+		opt := &gitlab.ListProjectsOptions{Topic: gitlab.String(query), ListOptions: listOpt}
+		return s.GitLab.Projects.ListProjects(opt, gitlab.WithContext(reqCtx))
+	})
+}
+
+// ListGitLabGroupProjects returns up to maxResults projects in the group with
+// the full path group and its subgroups, most recently active first. Each
+// result's Query is group.
+func (s *Searcher) ListGitLabGroupProjects(ctx context.Context, group string, maxResults int) ([]Result, error) {
+	return s.listGitLabProjects(ctx, CategoryRepo, group, maxResults, func(reqCtx context.Context, listOpt gitlab.ListOptions) ([]*gitlab.Project, *gitlab.Response, error) {
+		opt := &gitlab.ListGroupProjectsOptions{OrderBy: gitlab.String("last_activity_at"), IncludeSubgroups: gitlab.Bool(true), ListOptions: listOpt}
+		return s.GitLab.Groups.ListGroupProjects(group, opt, gitlab.WithContext(reqCtx))
+	})
+}
+
+// listGitLabProjects pages through list until it has maxResults projects,
+// returning them as results in category.
+func (s *Searcher) listGitLabProjects(ctx context.Context, category, query string, maxResults int, list func(context.Context, gitlab.ListOptions) ([]*gitlab.Project, *gitlab.Response, error)) ([]Result, error) {
+	var projectResults []Result
+	listOpt := gitlab.ListOptions{PerPage: perPage(maxResults, maxPerPage)}
+	for {
+		reqCtx, cancel := s.requestContext(ctx)
+		projects, resp, err := list(reqCtx, listOpt)
+		cancel()
+		if err != nil {
+			return nil, err
+		}
+
+		from := len(projectResults)
+		for _, project := range projects {
+			projectResults = append(projectResults, Result{Platform: PlatformGitLab, Category: category, Query: query, Name: project.PathWithNamespace, URL: project.WebURL,
+				Description: project.Description, Stars: project.StarCount, LastActive: timeValue(project.LastActivityAt), Fork: project.ForkedFromProject != nil, Archived: project.Archived})
+		}
+		emitPage(ctx, projectResults, from, maxResults)
+
+		if len(projectResults) >= maxResults || resp.NextPage == 0 {
+			break
+		}
+		listOpt.Page = resp.NextPage
+	}
+
+	return truncate(projectResults, maxResults), nil
+}
+
+// SearchGitLabSnippets returns up to maxResults public snippets whose title
+// matches query. Each result is named by its URL and described by its title.
+func (s *Searcher) SearchGitLabSnippets(ctx context.Context, query string, maxResults int) ([]Result, error) {
+	var snippetResults []Result
+	opt := &gitlab.SearchOptions{ListOptions: gitlab.ListOptions{PerPage: perPage(maxResults, maxPerPage)}}
+	for {
+		reqCtx, cancel := s.requestContext(ctx)
+		snippets, resp, err := s.GitLab.Search.SnippetTitles(query, opt, gitlab.WithContext(reqCtx))
+		cancel()
+		if err != nil {
+			if resp != nil && (resp.StatusCode == http.StatusForbidden || resp.StatusCode == http.StatusBadRequest) {
+				return nil, ErrSnippetSearchDenied
+			}
+			return nil, err
+		}
+
+		from := len(snippetResults)
+		for _, snippet := range snippets {
+			snippetResults = append(snippetResults, Result{Platform: PlatformGitLab, Category: CategorySnippet, Query: query, Name: snippet.WebURL, URL: snippet.WebURL,
+				Description: snippet.Title, LastActive: timeValue(snippet.UpdatedAt)})
+		}
+		emitPage(ctx, snippetResults, from, maxResults)
+
+		if len(snippetResults) >= maxResults || resp.NextPage == 0 {
+			break
+		}
+		opt.Page = resp.NextPage
+	}
+
+	return truncate(snippetResults, maxResults), nil
+}
+
+func (s *Searcher) authGitLab(ctx context.Context) error {
+	_, resp, err := s.GitLab.Users.CurrentUser(gitlab.WithContext(ctx))
+	if resp != nil {
+		return authError(resp.Response, err)
+	}
+	return err
+}
+
+func (s *Searcher) checkGitLab(ctx context.Context, category, name string) ([]Result, error) {
+	reqCtx, cancel := s.requestContext(ctx)
+	defer cancel()
+
+	var found Result
+	var resp *gitlab.Response
+	var err error
+	switch category {
+	case CategoryOrg:
+		var group *gitlab.Group
+		if group, resp, err = s.GitLab.Groups.GetGroup(name, gitlab.WithContext(reqCtx)); err == nil {
+			found = Result{Name: group.FullPath, URL: group.WebURL, Description: group.Description}
+		}
+	case CategoryRepo:
+		var project *gitlab.Project
+		if project, resp, err = s.GitLab.Projects.GetProject(name, nil, gitlab.WithContext(reqCtx)); err == nil {
+			found = Result{Name: project.PathWithNamespace, URL: project.WebURL,
+				Description: project.Description, Stars: project.StarCount, LastActive: timeValue(project.LastActivityAt), Fork: project.ForkedFromProject != nil, Archived: project.Archived}
+		}
+	case CategoryUser:
+		// GitLab has no lookup by username, but filtering the user list by
+		// username is an exact match.
+		var users []*gitlab.User
+		if users, resp, err = s.GitLab.Users.ListUsers(&gitlab.ListUsersOptions{Username: gitlab.String(name)}, gitlab.WithContext(reqCtx)); err == nil {
+			if len(users) == 0 {
+				return nil, nil
+			}
+			found = Result{Name: users[0].Username, URL: users[0].WebURL, Description: users[0].Bio, LastActive: isoTime(users[0].LastActivityOn), DisplayName: users[0].Name}
+		}
+	default:
+		return nil, ErrUnsupported
+	}
+
+	if err != nil {
+		if resp != nil && isNotFound(resp.Response) {
+			return nil, nil
+		}
+		return nil, err
+	}
+
+	found.Platform, found.Category, found.Query = PlatformGitLab, category, name
+	return []Result{found}, nil
+}
+
+func timeValue(t *time.Time) time.Time {
+	if t == nil {
+		return time.Time{}
+	}
+	return *t
+}
+
+func isoTime(t *gitlab.ISOTime) time.Time {
+	if t == nil {
+		return time.Time{}
+	}
+	return time.Time(*t)
+}
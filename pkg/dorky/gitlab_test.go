@@ -0,0 +1,154 @@
+package dorky
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"net/http"
+	"net/http/httptest"
+	"testing"
+)
+
+// newGitLabTestSearcher returns a Searcher whose GitLab client sends every
+// API request to handler. go-gitlab's own probe of the API root, which it
+// sends once to read the instance's rate limit, is answered separately.
+func newGitLabTestSearcher(t *testing.T, handler http.HandlerFunc) *Searcher {
+	t.Helper()
+	server := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		if r.URL.Path != "/api/v4/" {
+			handler(w, r)
+		}
+	}))
+	t.Cleanup(server.Close)
+
+	client, err := NewGitLabClient("token", server.URL, ClientOptions{Transport: server.Client().Transport})
+	if err != nil {
+		t.Fatal(err)
+	}
+	return &Searcher{GitLab: client}
+}
+
+func TestSearchGitLabGroups(t *testing.T) {
+	s := newGitLabTestSearcher(t, func(w http.ResponseWriter, r *http.Request) {
+		if r.URL.Path != "/api/v4/groups" {
+			t.Errorf("request to %s, want /api/v4/groups", r.URL.Path)
+		}
+		q := r.URL.Query()
+		if q.Get("search") != "acme" || q.Get("all_available") != "true" {
+			t.Errorf("query = %s, want search=acme and all_available=true", r.URL.RawQuery)
+		}
+		fmt.Fprint(w, `[{"full_path": "acme", "web_url": "https://gitlab.com/groups/acme", "description": "Acme Inc"}]`)
+	})
+
+	results, err := s.Search(context.Background(), PlatformGitLab, CategoryOrg, "acme", 10)
+	if err != nil {
+		t.Fatal(err)
+	}
+	want := Result{Platform: PlatformGitLab, Category: CategoryOrg, Query: "acme", Name: "acme", URL: "https://gitlab.com/groups/acme", Description: "Acme Inc"}
+	if len(results) != 1 || results[0] != want {
+		t.Errorf("results = %+v, want [%+v]", results, want)
+	}
+}
+
+func TestSearchGitLabProjects(t *testing.T) {
+	s := newGitLabTestSearcher(t, func(w http.ResponseWriter, r *http.Request) {
+		if r.URL.Path != "/api/v4/groups/acme/projects" {
+			t.Errorf("request to %s, want /api/v4/groups/acme/projects", r.URL.Path)
+		}
+		if q := r.URL.Query(); q.Get("search") != "api" || q.Get("include_subgroups") != "true" {
+			t.Errorf("query = %s, want search=api and include_subgroups=true", r.URL.RawQuery)
+		}
+		fmt.Fprint(w, `[{
+			"path_with_namespace": "acme/platform/api",
+			"web_url": "https://gitlab.com/acme/platform/api",
+			"description": "The Acme API",
+			"star_count": 7,
+			"last_activity_at": "2021-03-04T05:06:07Z",
+			"archived": true,
+			"forked_from_project": {"id": 1}
+		}]`)
+	})
+	s.Org = "acme"
+
+	results, err := s.Search(context.Background(), PlatformGitLab, CategoryRepo, "api", 10)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if len(results) != 1 {
+		t.Fatalf("got %d results, want 1", len(results))
+	}
+
+	r := results[0]
+	if r.Name != "acme/platform/api" || r.URL != "https://gitlab.com/acme/platform/api" || r.Description != "The Acme API" {
+		t.Errorf("result = %+v, want acme/platform/api with its URL and description", r)
+	}
+	if r.Stars != 7 || !r.Fork || !r.Archived || r.LastActive.IsZero() {
+		t.Errorf("result = %+v, want 7 stars, forked, archived, and last active", r)
+	}
+}
+
+func TestSearchGitLabErrors(t *testing.T) {
+	tests := []struct {
+		name   string
+		status int
+	}{
+		{"bad request", http.StatusBadRequest},
+		{"forbidden", http.StatusForbidden},
+		{"server error", http.StatusInternalServerError},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			s := newGitLabTestSearcher(t, func(w http.ResponseWriter, r *http.Request) {
+				w.WriteHeader(tt.status)
+				fmt.Fprint(w, `{"message": "failed"}`)
+			})
+
+			results, err := s.Search(context.Background(), PlatformGitLab, CategoryUser, "acme", 10)
+			if err == nil {
+				t.Fatalf("got %d results and no error, want an error", len(results))
+			}
+		})
+	}
+}
+
+func TestAuthenticateGitLab(t *testing.T) {
+	s := newGitLabTestSearcher(t, func(w http.ResponseWriter, r *http.Request) {
+		w.WriteHeader(http.StatusUnauthorized)
+		fmt.Fprint(w, `{"message": "401 Unauthorized"}`)
+	})
+
+	if err := s.Authenticate(context.Background(), PlatformGitLab); !errors.Is(err, ErrAuth) {
+		t.Errorf("Authenticate = %v, want ErrAuth", err)
+	}
+}
+
+func TestCheckGitLabNotFound(t *testing.T) {
+	s := newGitLabTestSearcher(t, func(w http.ResponseWriter, r *http.Request) {
+		w.WriteHeader(http.StatusNotFound)
+		fmt.Fprint(w, `{"message": "404 Group Not Found"}`)
+	})
+
+	results, err := s.Check(context.Background(), PlatformGitLab, CategoryOrg, "acme")
+	if err != nil || len(results) != 0 {
+		t.Errorf("Check = %v, %v, want no results and no error", results, err)
+	}
+}
+
+func TestSearchGitLabGroupsNestedSubgroup(t *testing.T) {
+	s := newGitLabTestSearcher(t, func(w http.ResponseWriter, r *http.Request) {
+		q := r.URL.Query()
+		if q.Get("top_level_only") != "false" {
+			t.Errorf("query = %s, want top_level_only=false so subgroups are searched", r.URL.RawQuery)
+		}
+		// Only the innermost subgroup matches the word.
+		fmt.Fprint(w, `[{"path": "secops", "full_path": "acme/platform/infra/secops", "web_url": "https://gitlab.com/groups/acme/platform/infra/secops"}]`)
+	})
+
+	results, err := s.Search(context.Background(), PlatformGitLab, CategoryOrg, "secops", 10)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if len(results) != 1 || results[0].Name != "acme/platform/infra/secops" || results[0].URL != "https://gitlab.com/groups/acme/platform/infra/secops" {
+		t.Errorf("results = %+v, want the subgroup by its full path", results)
+	}
+}
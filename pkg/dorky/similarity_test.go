@@ -0,0 +1,44 @@
+package dorky
+
+import (
+	"math"
+	"testing"
+)
+
+func TestSimilarity(t *testing.T) {
+	tests := []struct {
+		a, b string
+		want float64
+	}{
+		{"acme", "acme", 1},
+		{"Acme", "ACME", 1},
+		{"", "", 1},
+		{"acme", "", 0},
+		{"", "acme", 0},
+		{"abc", "xyz", 0},
+		{"martha", "marhta", 0.961},
+		{"dwayne", "duane", 0.84},
+		{"dixon", "dicksonx", 0.813},
+		{"café", "cafe", 0.883},
+	}
+	for _, tt := range tests {
+		if got := Similarity(tt.a, tt.b); math.Abs(got-tt.want) > 0.001 {
+			t.Errorf("Similarity(%q, %q) = %.3f, want %.3f", tt.a, tt.b, got, tt.want)
+		}
+		if got, reversed := Similarity(tt.a, tt.b), Similarity(tt.b, tt.a); got != reversed {
+			t.Errorf("Similarity(%q, %q) = %.3f, but %.3f reversed", tt.a, tt.b, got, reversed)
+		}
+	}
+}
+
+func TestSimilarityEditDistance(t *testing.T) {
+	// Each edit away from the query lowers the score.
+	query := "acme"
+	names := []string{"acme", "acmes", "acme-io", "acme-corp", "xacme-corp"}
+	for i := 1; i < len(names); i++ {
+		closer, further := Similarity(names[i-1], query), Similarity(names[i], query)
+		if closer <= further {
+			t.Errorf("Similarity(%q) = %.3f, not above Similarity(%q) = %.3f", names[i-1], closer, names[i], further)
+		}
+	}
+}
@@ -0,0 +1,199 @@
+package dorky
+
+import (
+	"bytes"
+	"context"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"net/http"
+	"strings"
+	"time"
+)
+
+const (
+	sourceHutGitURL  = "https://git.sr.ht"
+	sourceHutMetaURL = "https://sr.ht"
+)
+
+// SourceHutClient is a minimal client for the git.sr.ht GraphQL API.
+type SourceHutClient struct {
+	client  *http.Client
+	baseURL string
+	token   string
+}
+
+type sourceHutRepository struct {
+	Name        string    `json:"name"`
+	Description string    `json:"description"`
+	Updated     time.Time `json:"updated"`
+}
+
+type sourceHutUser struct {
+	CanonicalName string `json:"canonicalName"`
+	Username      string `json:"username"`
+	Repositories  struct {
+		Results []sourceHutRepository `json:"results"`
+		Cursor  *string               `json:"cursor"`
+	} `json:"repositories"`
+	Repository *sourceHutRepository `json:"repository"`
+}
+
+const sourceHutUserQuery = `query($username: String!) {
+	user(username: $username) { canonicalName username }
+}`
+
+// sourceHutMeQuery aliases the authenticated user as "user" so it decodes
+// like the other queries.
+const sourceHutMeQuery = `query {
+	user: me { canonicalName username }
+}`
+
+const sourceHutRepositoriesQuery = `query($username: String!, $cursor: Cursor) {
+	user(username: $username) {
+		canonicalName
+		repositories(cursor: $cursor) { results { name description updated } cursor }
+	}
+}`
+
+const sourceHutRepositoryQuery = `query($username: String!, $name: String!) {
+	user(username: $username) {
+		canonicalName
+		repository(name: $name) { name description updated }
+	}
+}`
+
+// NewSourceHutClient returns a git.sr.ht client authenticated with a personal
+// access token.
+func NewSourceHutClient(token string, opts ClientOptions) (*SourceHutClient, error) {
+	if token == "" {
+		return nil, errors.New("SourceHut token is empty")
+	}
+
+	return &SourceHutClient{
+		client:  &http.Client{Transport: newTransport(opts.baseTransport(), nil, opts)},
+		baseURL: sourceHutGitURL,
+		token:   token,
+	}, nil
+}
+
+// user runs a GraphQL query selecting a single user. A user that does not
+// exist is returned as nil.
+func (c *SourceHutClient) user(ctx context.Context, query string, variables map[string]interface{}) (*sourceHutUser, error) {
+	body, err := json.Marshal(map[string]interface{}{"query": query, "variables": variables})
+	if err != nil {
+		return nil, err
+	}
+
+	req, err := http.NewRequestWithContext(ctx, http.MethodPost, c.baseURL+"/query", bytes.NewReader(body))
+	if err != nil {
+		return nil, err
+	}
+	req.Header.Set("Authorization", "Bearer "+c.token)
+	req.Header.Set("Content-Type", "application/json")
+
+	resp, err := c.client.Do(req)
+	if err != nil {
+		return nil, err
+	}
+	defer resp.Body.Close()
+
+	if resp.StatusCode < 200 || resp.StatusCode > 299 {
+		return nil, authError(resp, fmt.Errorf("sourcehut: unexpected response %s from %s", resp.Status, req.URL))
+	}
+
+	var result struct {
+		Data struct {
+			User *sourceHutUser `json:"user"`
+		} `json:"data"`
+		Errors []struct {
+			Message string `json:"message"`
+		} `json:"errors"`
+	}
+	if err := json.NewDecoder(resp.Body).Decode(&result); err != nil {
+		return nil, err
+	}
+	if len(result.Errors) > 0 {
+		return nil, fmt.Errorf("sourcehut: %s", result.Errors[0].Message)
+	}
+	return result.Data.User, nil
+}
+
+// sourceHutUsername strips the "~" SourceHut puts before usernames.
+func sourceHutUsername(name string) string {
+	return strings.TrimPrefix(name, "~")
+}
+
+// SearchSourceHutUsers returns the user named query, if it exists. SourceHut
+// has no search API, so users are resolved with a direct lookup of the query.
+func (s *Searcher) SearchSourceHutUsers(ctx context.Context, query string) ([]Result, error) {
+	return s.lookup(ctx, s.checkSourceHut, CategoryUser, query)
+}
+
+// SearchSourceHutRepositories returns up to maxResults public repositories
+// owned by the user named query. SourceHut has no repository search, so this
+// lists the repositories of the matching user instead.
+func (s *Searcher) SearchSourceHutRepositories(ctx context.Context, query string, maxResults int) ([]Result, error) {
+	variables := map[string]interface{}{"username": sourceHutUsername(query)}
+
+	var repos []Result
+	for {
+		reqCtx, cancel := s.requestContext(ctx)
+		user, err := s.SourceHut.user(reqCtx, sourceHutRepositoriesQuery, variables)
+		cancel()
+		if err != nil {
+			return nil, err
+		}
+		if user == nil {
+			return nil, nil
+		}
+
+		from := len(repos)
+		for _, repo := range user.Repositories.Results {
+			repos = append(repos, Result{Platform: PlatformSourceHut, Category: CategoryRepo, Query: query, Name: user.CanonicalName + "/" + repo.Name,
+				URL: s.SourceHut.baseURL + "/" + user.CanonicalName + "/" + repo.Name, Description: repo.Description, LastActive: repo.Updated})
+		}
+		emitPage(ctx, repos, from, maxResults)
+
+		if len(repos) >= maxResults || user.Repositories.Cursor == nil {
+			break
+		}
+		variables["cursor"] = *user.Repositories.Cursor
+	}
+
+	return truncate(repos, maxResults), nil
+}
+
+func (s *Searcher) checkSourceHut(ctx context.Context, category, name string) ([]Result, error) {
+	reqCtx, cancel := s.requestContext(ctx)
+	defer cancel()
+
+	var found Result
+	switch category {
+	case CategoryRepo:
+		owner, repoName := splitRepoPath(name)
+		user, err := s.SourceHut.user(reqCtx, sourceHutRepositoryQuery, map[string]interface{}{"username": sourceHutUsername(owner), "name": repoName})
+		if err != nil {
+			return nil, err
+		}
+		if user == nil || user.Repository == nil {
+			return nil, nil
+		}
+		found = Result{Name: user.CanonicalName + "/" + user.Repository.Name, URL: s.SourceHut.baseURL + "/" + user.CanonicalName + "/" + user.Repository.Name,
+			Description: user.Repository.Description, LastActive: user.Repository.Updated}
+	case CategoryUser:
+		user, err := s.SourceHut.user(reqCtx, sourceHutUserQuery, map[string]interface{}{"username": sourceHutUsername(name)})
+		if err != nil {
+			return nil, err
+		}
+		if user == nil {
+			return nil, nil
+		}
+		found = Result{Name: user.Username, URL: sourceHutMetaURL + "/" + user.CanonicalName}
+	default:
+		return nil, ErrUnsupported
+	}
+
+	found.Platform, found.Category, found.Query = PlatformSourceHut, category, name
+	return []Result{found}, nil
+}
@@ -0,0 +1,279 @@
+package dorky
+
+import (
+	"errors"
+	"fmt"
+	"io"
+	"net/http"
+	"net/url"
+	"strconv"
+	"time"
+
+	"golang.org/x/time/rate"
+)
+
+// ClientOptions configures the HTTP behaviour shared by every platform client.
+type ClientOptions struct {
+	// Retries is the number of times a rate-limited request is retried. GitLab
+	// clients also retry GET requests that fail with a 5xx status.
+	Retries int
+
+	// Timeout bounds each request for clients that cannot take a per-call
+	// context, such as Gitea. Zero means no timeout.
+	Timeout time.Duration
+
+	// Logf, if set, receives progress messages such as rate limit retries.
+	Logf func(format string, a ...interface{})
+
+	// Debugf, if set, receives a line for every HTTP request sent, with its
+	// status and remaining rate limit.
+	Debugf func(format string, a ...interface{})
+
+	// Proxy, if set, routes all requests through this proxy. Otherwise the
+	// HTTP_PROXY, HTTPS_PROXY, and NO_PROXY environment variables apply.
+	Proxy *url.URL
+
+	// Transport, if set, sends requests in place of a network transport, so
+	// tests can answer them from an httptest.Server or a stub. Retries, rate
+	// limiting, and logging still wrap it. Proxy is ignored.
+	Transport http.RoundTripper
+
+	// Delay, if positive, is the least time between the start of successive
+	// requests from one client, on top of any platform rate limit. It is
+	// shared by every concurrent search using the client.
+	Delay time.Duration
+
+	// ETagDir, if set, stores responses that carry an ETag in this directory
+	// and revalidates them with conditional requests on later runs.
+	ETagDir string
+}
+
+func (o ClientOptions) logf(format string, a ...interface{}) {
+	if o.Logf != nil {
+		o.Logf(format, a...)
+	}
+}
+
+func (o ClientOptions) debugf(format string, a ...interface{}) {
+	if o.Debugf != nil {
+		o.Debugf(format, a...)
+	}
+}
+
+// baseTransport returns the transport that actually sends requests, honouring
+// the configured or environment proxy.
+func (o ClientOptions) baseTransport() http.RoundTripper {
+	if o.Transport != nil {
+		return &loggingTransport{transport: o.Transport, debugf: o.debugf}
+	}
+
+	transport := http.DefaultTransport.(*http.Transport).Clone()
+	if o.Proxy != nil {
+		transport.Proxy = http.ProxyURL(o.Proxy)
+	} else {
+		transport.Proxy = http.ProxyFromEnvironment
+	}
+	return &loggingTransport{transport: transport, debugf: o.debugf}
+}
+
+// newTransport wraps base with retries, conditional requests if opts.ETagDir
+// is set, and, if limiter is not nil, a rate limiter shared by every request
+// the client makes. opts.Delay adds a second limiter spacing those requests.
+func newTransport(base http.RoundTripper, limiter *rate.Limiter, opts ClientOptions) *retryTransport {
+	if limiter != nil {
+		base = &rateLimitedTransport{transport: base, limiter: limiter}
+	}
+	if opts.Delay > 0 {
+		base = &rateLimitedTransport{transport: base, limiter: rate.NewLimiter(rate.Every(opts.Delay), 1)}
+	}
+	if opts.ETagDir != "" {
+		base = &etagTransport{transport: base, dir: opts.ETagDir, logf: opts.logf}
+	}
+	return &retryTransport{
+		transport: base,
+		retries:   opts.Retries,
+		logf:      opts.logf,
+	}
+}
+
+// NewHTTPClient returns a client for requests outside the platform APIs,
+// such as notifications, with the same proxy, retries, logging, and Transport
+// as the platform clients created with opts.
+func NewHTTPClient(opts ClientOptions) *http.Client {
+	return &http.Client{
+		Transport: newTransport(opts.baseTransport(), nil, opts),
+		Timeout:   opts.Timeout,
+	}
+}
+
+// loggingTransport reports each request and the rate limit budget left after
+// it. GitHub prefixes its rate limit headers with X-, GitLab does not.
+type loggingTransport struct {
+	transport http.RoundTripper
+	debugf    func(format string, a ...interface{})
+}
+
+func (t *loggingTransport) RoundTrip(req *http.Request) (*http.Response, error) {
+	resp, err := t.transport.RoundTrip(req)
+	if err != nil {
+		t.debugf("%s %s: %s\n", req.Method, req.URL, err)
+		return nil, err
+	}
+
+	remaining := resp.Header.Get("X-RateLimit-Remaining")
+	if remaining == "" {
+		remaining = resp.Header.Get("RateLimit-Remaining")
+	}
+	if remaining != "" {
+		t.debugf("%s %s: %s (rate limit remaining: %s)\n", req.Method, req.URL, resp.Status, remaining)
+	} else {
+		t.debugf("%s %s: %s\n", req.Method, req.URL, resp.Status)
+	}
+	return resp, nil
+}
+
+type rateLimitedTransport struct {
+	transport http.RoundTripper
+	limiter   *rate.Limiter
+}
+
+func (t *rateLimitedTransport) RoundTrip(req *http.Request) (*http.Response, error) {
+	if err := t.limiter.Wait(req.Context()); err != nil {
+		return nil, err
+	}
+
+	return t.transport.RoundTrip(req)
+}
+
+// retryTransport retries requests rejected by a platform's rate limiter,
+// waiting for the duration the platform asks for or, failing that, an
+// exponential backoff.
+type retryTransport struct {
+	transport http.RoundTripper
+	retries   int
+	logf      func(format string, a ...interface{})
+
+	// serverErrors also retries GET requests answered with a 5xx status,
+	// which busy self-hosted GitLab instances return intermittently.
+	serverErrors bool
+}
+
+func (t *retryTransport) RoundTrip(req *http.Request) (*http.Response, error) {
+	for attempt := 0; ; attempt++ {
+		resp, err := t.transport.RoundTrip(req)
+		if err != nil {
+			return nil, err
+		}
+
+		reason := "Rate limited by " + req.URL.Host
+		wait, ok := retryDelay(resp, attempt)
+		if !ok && t.serverErrors && (req.Method == http.MethodGet || req.Method == http.MethodHead) {
+			reason = fmt.Sprintf("%s returned %s", req.URL.Host, resp.Status)
+			wait, ok = serverErrorDelay(resp, attempt)
+		}
+		if !ok || attempt >= t.retries {
+			return resp, nil
+		}
+
+		if req.Body != nil {
+			if req.GetBody == nil {
+				return resp, nil
+			}
+			if req.Body, err = req.GetBody(); err != nil {
+				return resp, nil
+			}
+		}
+
+		io.Copy(io.Discard, resp.Body)
+		resp.Body.Close()
+
+		t.logf("%s, retrying in %s (%d/%d)\n", reason, wait, attempt+1, t.retries)
+
+		timer := time.NewTimer(wait)
+		select {
+		case <-req.Context().Done():
+			timer.Stop()
+			return nil, req.Context().Err()
+		case <-timer.C:
+		}
+	}
+}
+
+// retryDelay reports how long to wait before retrying resp, if at all. GitHub
+// signals primary rate limits with a 403 and an exhausted X-RateLimit-Remaining
+// header, secondary limits with Retry-After, and GitLab uses 429.
+func retryDelay(resp *http.Response, attempt int) (time.Duration, bool) {
+	if resp.StatusCode != http.StatusForbidden && resp.StatusCode != http.StatusTooManyRequests {
+		return 0, false
+	}
+
+	if seconds, err := strconv.Atoi(resp.Header.Get("Retry-After")); err == nil {
+		return time.Duration(seconds) * time.Second, true
+	}
+
+	if resp.Header.Get("X-RateLimit-Remaining") == "0" {
+		if reset, err := strconv.ParseInt(resp.Header.Get("X-RateLimit-Reset"), 10, 64); err == nil {
+			wait := time.Until(time.Unix(reset, 0)) + time.Second
+			if wait < time.Second {
+				wait = time.Second
+			}
+			return wait, true
+		}
+	}
+
+	if resp.StatusCode == http.StatusTooManyRequests {
+		return time.Second << uint(attempt), true
+	}
+
+	return 0, false
+}
+
+// serverErrorDelay reports how long to wait before retrying a 5xx response,
+// if at all: Retry-After when the server sent one, otherwise a delay that
+// doubles with each attempt. 4xx responses are the client's fault and are not
+// retried.
+func serverErrorDelay(resp *http.Response, attempt int) (time.Duration, bool) {
+	if resp.StatusCode < 500 || resp.StatusCode == http.StatusNotImplemented {
+		return 0, false
+	}
+	if seconds, err := strconv.Atoi(resp.Header.Get("Retry-After")); err == nil {
+		return time.Duration(seconds) * time.Second, true
+	}
+	return time.Second << uint(attempt), true
+}
+
+// ValidateBaseURL checks that rawURL is an absolute http(s) URL, as required
+// for self-hosted instances.
+func ValidateBaseURL(rawURL string) error {
+	if rawURL == "" {
+		return errors.New("is not set")
+	}
+
+	u, err := url.Parse(rawURL)
+	if err != nil {
+		return fmt.Errorf("is invalid: %s", err)
+	}
+
+	if u.Scheme != "http" && u.Scheme != "https" {
+		return fmt.Errorf("must use http or https, got %q", rawURL)
+	}
+
+	if u.Host == "" {
+		return fmt.Errorf("must include a host, got %q", rawURL)
+	}
+
+	return nil
+}
+
+// authError wraps err in ErrAuth when resp shows the credentials were
+// rejected or lack the permissions needed.
+func authError(resp *http.Response, err error) error {
+	if err != nil && resp != nil && (resp.StatusCode == http.StatusUnauthorized || resp.StatusCode == http.StatusForbidden) {
+		return fmt.Errorf("%w: %s", ErrAuth, err)
+	}
+	return err
+}
+
+func isNotFound(resp *http.Response) bool {
+	return resp != nil && resp.StatusCode == http.StatusNotFound
+}
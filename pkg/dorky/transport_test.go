@@ -0,0 +1,72 @@
+package dorky
+
+import (
+	"context"
+	"io"
+	"net/http"
+	"strings"
+	"testing"
+)
+
+// roundTripFunc is an http.RoundTripper backed by a function.
+type roundTripFunc func(*http.Request) (*http.Response, error)
+
+func (f roundTripFunc) RoundTrip(req *http.Request) (*http.Response, error) {
+	return f(req)
+}
+
+func TestRetryServerErrors(t *testing.T) {
+	tests := []struct {
+		name         string
+		method       string
+		statuses     []int
+		serverErrors bool
+		wantStatus   int
+		wantCalls    int
+	}{
+		{"503 then 200", http.MethodGet, []int{503, 200}, true, 200, 2},
+		{"500 twice then 200", http.MethodGet, []int{500, 500, 200}, true, 200, 3},
+		{"retries exhausted", http.MethodGet, []int{503, 503, 503, 503, 200}, true, 503, 4},
+		{"HEAD retried", http.MethodHead, []int{502, 200}, true, 200, 2},
+		{"POST not retried", http.MethodPost, []int{503, 200}, true, 503, 1},
+		{"501 not retried", http.MethodGet, []int{501, 200}, true, 501, 1},
+		{"404 not retried", http.MethodGet, []int{404, 200}, true, 404, 1},
+		{"off for other clients", http.MethodGet, []int{503, 200}, false, 503, 1},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			calls := 0
+			stub := roundTripFunc(func(req *http.Request) (*http.Response, error) {
+				status := tt.statuses[calls]
+				calls++
+				// Retry-After: 0 keeps the test from waiting out the backoff.
+				header := http.Header{"Retry-After": {"0"}}
+				return &http.Response{StatusCode: status, Header: header, Body: http.NoBody, Request: req}, nil
+			})
+
+			opts := ClientOptions{Retries: 3, Transport: stub}
+			transport := newTransport(opts.baseTransport(), nil, opts)
+			transport.serverErrors = tt.serverErrors
+
+			var body io.Reader
+			if tt.method == http.MethodPost {
+				body = strings.NewReader("{}")
+			}
+			req, err := http.NewRequestWithContext(context.Background(), tt.method, "https://gitlab.example.com/api/v4/groups", body)
+			if err != nil {
+				t.Fatal(err)
+			}
+
+			resp, err := transport.RoundTrip(req)
+			if err != nil {
+				t.Fatal(err)
+			}
+			if resp.StatusCode != tt.wantStatus {
+				t.Errorf("status = %d, want %d", resp.StatusCode, tt.wantStatus)
+			}
+			if calls != tt.wantCalls {
+				t.Errorf("sent %d requests, want %d", calls, tt.wantCalls)
+			}
+		})
+	}
+}
@@ -0,0 +1,79 @@
+package main
+
+import (
+	"net"
+	"strings"
+
+	"golang.org/x/net/publicsuffix"
+)
+
+var (
+	// seedSuffixes are appended to a domain's name to guess at account
+	// names such as acmeinc or acmehq.
+	seedSuffixes = []string{"inc", "hq", "corp", "labs", "io", "dev"}
+
+	// seedCorporateWords are dropped from the end of a domain's name, so
+	// acme-corp.com also suggests acme.
+	seedCorporateWords = map[string]bool{
+		"co": true, "corp": true, "inc": true, "llc": true, "ltd": true,
+		"group": true, "hq": true, "labs": true, "software": true, "tech": true,
+	}
+)
+
+// maxSeeds bounds how many candidates one domain produces.
+const maxSeeds = 16
+
+// domainSeeds returns organization and user name candidates for a domain,
+// URL, or hostname, such as acme-corp, acmecorp, acme_corp, acme, and
+// acmecorpinc for acme-corp.com. Subdomains and the public suffix are
+// ignored. It returns nil if word is not a domain, or is an IP address or git
+// hosting URL, which -c already handles.
+func domainSeeds(word string) []string {
+	host, _ := splitLocation(word)
+	if host == "" || gitHosts[host] || net.ParseIP(host) != nil {
+		return nil
+	}
+
+	domain, err := publicsuffix.EffectiveTLDPlusOne(host)
+	if err != nil {
+		return nil
+	}
+	suffix, _ := publicsuffix.PublicSuffix(domain)
+	name := strings.TrimSuffix(domain, "."+suffix)
+
+	tokens := strings.FieldsFunc(name, func(r rune) bool {
+		return r == '-' || r == '_'
+	})
+	if len(tokens) == 0 {
+		return nil
+	}
+	joined := strings.Join(tokens, "")
+
+	var seeds []string
+	add := func(seed string) {
+		if len(seeds) < maxSeeds && seed != "" && !containsString(seeds, seed) {
+			seeds = append(seeds, seed)
+		}
+	}
+
+	add(name)
+	add(joined)
+	add(strings.Join(tokens, "-"))
+	add(strings.Join(tokens, "_"))
+
+	if len(tokens) > 1 {
+		if seedCorporateWords[tokens[len(tokens)-1]] {
+			base := tokens[:len(tokens)-1]
+			add(strings.Join(base, ""))
+			add(strings.Join(base, "-"))
+		}
+		add(tokens[0])
+	}
+
+	for _, s := range seedSuffixes {
+		if !strings.HasSuffix(joined, s) {
+			add(joined + s)
+		}
+	}
+	return seeds
+}
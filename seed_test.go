@@ -0,0 +1,46 @@
+package main
+
+import (
+	"strings"
+	"testing"
+)
+
+func TestDomainSeeds(t *testing.T) {
+	acme := []string{"acme", "acmeinc", "acmehq", "acmecorp", "acmelabs", "acmeio", "acmedev"}
+	tests := []struct {
+		in   string
+		want []string
+	}{
+		{"acme-corp.com", []string{"acme-corp", "acmecorp", "acme_corp", "acme", "acmecorpinc", "acmecorphq", "acmecorplabs", "acmecorpio", "acmecorpdev"}},
+		{"acme.com", acme},
+		// Multi-part public suffixes and subdomains are dropped.
+		{"acme.co.uk", acme},
+		{"api.acme.co.uk", acme},
+		{"dev.eu.acme.io:8443", acme},
+		{"https://shop.acme-inc.com.au/path", []string{"acme-inc", "acmeinc", "acme_inc", "acme", "acmeinchq", "acmeinccorp", "acmeinclabs", "acmeincio", "acmeincdev"}},
+		// Git hosting URLs, IP addresses, and plain words are left to -c.
+		{"github.com", nil},
+		{"https://gitlab.com/acme", nil},
+		{"10.0.0.1", nil},
+		{"acme", nil},
+	}
+	for _, tt := range tests {
+		if got := domainSeeds(tt.in); strings.Join(got, "|") != strings.Join(tt.want, "|") {
+			t.Errorf("domainSeeds(%q) = %q, want %q", tt.in, got, tt.want)
+		}
+	}
+}
+
+func TestDomainSeedsLimit(t *testing.T) {
+	for _, domain := range []string{"a-b-c-d-e-f-g-h.com", "alpha_beta-gamma-corp.co.uk"} {
+		seeds := domainSeeds(domain)
+		if len(seeds) == 0 || len(seeds) > maxSeeds {
+			t.Errorf("domainSeeds(%q) returned %d seeds, want 1 to %d", domain, len(seeds), maxSeeds)
+		}
+		for i, seed := range seeds {
+			if seed == "" || containsString(seeds[:i], seed) {
+				t.Errorf("domainSeeds(%q) returned empty or repeated seed %q", domain, seed)
+			}
+		}
+	}
+}
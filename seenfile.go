@@ -0,0 +1,84 @@
+package main
+
+import (
+	"errors"
+	"fmt"
+	"io/fs"
+	"os"
+	"strings"
+
+	"github.com/codingo/dorky/pkg/dorky"
+)
+
+var (
+	// seenBefore holds the identifiers of results reported by earlier runs
+	// and this one, read from -seen-file.
+	seenBefore = make(map[string]bool)
+
+	// seenFile is -seen-file opened for appending, or nil.
+	seenFile *os.File
+)
+
+// seenID returns the identifier -seen-file stores for r: its platform,
+// category, and name separated by tabs. Results from an additional GitLab
+// instance have the instance's host appended to the platform.
+func seenID(r dorky.Result) string {
+	platform := r.Platform
+	if r.Instance != "" {
+		platform += "@" + r.Instance
+	}
+	return platform + "\t" + r.Category + "\t" + r.Name
+}
+
+// openSeenFile reads the identifiers in path, one per line, and opens it for
+// appending the results this run reports. A missing file is created.
+func openSeenFile(path string) error {
+	data, err := os.ReadFile(path)
+	if err != nil && !errors.Is(err, fs.ErrNotExist) {
+		return err
+	}
+	for _, line := range strings.Split(string(data), "\n") {
+		if line = strings.TrimSpace(line); line != "" {
+			seenBefore[caseKey(line)] = true
+		}
+	}
+	verbosePrint("Loaded %d previously seen results from %s\n", len(seenBefore), path)
+
+	seenFile, err = openOutputFile(path, true)
+	return err
+}
+
+// removeSeenBefore drops results already reported, by an earlier run or
+// this one. Callers must hold outputMu.
+func removeSeenBefore(results []dorky.Result) []dorky.Result {
+	if seenFile == nil {
+		return results
+	}
+
+	var unseen []dorky.Result
+	for _, r := range results {
+		if !seenBefore[caseKey(seenID(r))] {
+			unseen = append(unseen, r)
+		}
+	}
+	return unseen
+}
+
+// recordSeen appends the results being reported to -seen-file. Callers must
+// hold outputMu.
+func recordSeen(results []dorky.Result) {
+	if seenFile == nil {
+		return
+	}
+
+	for _, r := range results {
+		id := seenID(r)
+		if seenBefore[caseKey(id)] {
+			continue
+		}
+		seenBefore[caseKey(id)] = true
+		if _, err := fmt.Fprintln(seenFile, id); err != nil {
+			errorPrint("Error writing seen file: %s\n", err)
+		}
+	}
+}
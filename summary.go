@@ -0,0 +1,92 @@
+package main
+
+import (
+	"fmt"
+	"strings"
+	"text/tabwriter"
+
+	"github.com/codingo/dorky/pkg/dorky"
+)
+
+var (
+	// wordSources maps each query to the input words it was generated from,
+	// and sourceWords lists the input words in the order they were read.
+	wordSources = make(map[string][]string)
+	sourceWords []string
+
+	// summary counts results per input word, keyed like counts.
+	summary = make(map[string]map[string]int)
+)
+
+// addSources records that query descends from each of sources.
+func addSources(query string, sources ...string) {
+	if query == "" {
+		return
+	}
+	for _, source := range sources {
+		if !containsString(wordSources[query], source) {
+			wordSources[query] = append(wordSources[query], source)
+		}
+	}
+}
+
+// addPermutationSources attributes each generated permutation to the inputs
+// whose tokens it contains. A permutation joins tokens from several inputs,
+// so it may count towards more than one of them.
+func addPermutationSources(inputs, generated []string) {
+	tokenSources := make(map[string][]string)
+	for _, input := range inputs {
+		for _, token := range strings.Fields(input) {
+			tokenSources[token] = append(tokenSources[token], wordSources[input]...)
+		}
+	}
+
+	for _, word := range generated {
+		for token, sources := range tokenSources {
+			if strings.Contains(word, token) {
+				addSources(word, sources...)
+			}
+		}
+	}
+}
+
+// tallySummary adds results for query to the summary of every input word it
+// descends from. Callers must hold outputMu.
+func tallySummary(platform, category, query string, n int) {
+	for _, source := range wordSources[query] {
+		if summary[source] == nil {
+			summary[source] = make(map[string]int)
+		}
+		summary[source][platform+"\x00"+category] += n
+	}
+}
+
+// printSummary prints a table of result counts per input word, with a
+// column for each platform and category searched.
+func printSummary(s *dorky.Searcher, cfg config) {
+	outputMu.Lock()
+	defer outputMu.Unlock()
+
+	var keys, labels []string
+	for _, platform := range searchedPlatforms(s) {
+		for _, category := range selectedCategories(cfg) {
+			if dorky.Supported(platform, category) {
+				keys = append(keys, platform+"\x00"+category)
+				labels = append(labels, categoryLabels[platform][category])
+			}
+		}
+	}
+
+	w := tabwriter.NewWriter(output, 0, 0, 2, ' ', 0)
+	fmt.Fprintf(w, "\nWord\t%s\tTotal\n", strings.Join(labels, "\t"))
+	for _, word := range sourceWords {
+		total := 0
+		fmt.Fprint(w, word)
+		for _, key := range keys {
+			fmt.Fprintf(w, "\t%d", summary[word][key])
+			total += summary[word][key]
+		}
+		fmt.Fprintf(w, "\t%d\n", total)
+	}
+	w.Flush()
+}
@@ -0,0 +1,30 @@
+package main
+
+import (
+	"unicode"
+
+	"golang.org/x/text/runes"
+	"golang.org/x/text/transform"
+	"golang.org/x/text/unicode/norm"
+)
+
+// newUnicodeFolder returns a transformer that decomposes its input, including
+// compatibility characters such as fullwidth letters, drops combining marks,
+// and recomposes what is left, so "ｃａｆé" becomes "cafe". A chain keeps state
+// between calls, so each caller needs its own.
+func newUnicodeFolder() transform.Transformer {
+	return transform.Chain(norm.NFKD, runes.Remove(runes.In(unicode.Mn)), norm.NFKC)
+}
+
+// normalizeUnicode returns s folded by newUnicodeFolder when
+// -normalize-unicode is set, and s unchanged otherwise.
+func normalizeUnicode(s string) string {
+	if !flags.normalizeUnicodeFlag {
+		return s
+	}
+	folded, _, err := transform.String(newUnicodeFolder(), s)
+	if err != nil {
+		return s
+	}
+	return folded
+}
@@ -0,0 +1,57 @@
+package main
+
+import (
+	"sync"
+	"testing"
+)
+
+func TestNormalizeUnicode(t *testing.T) {
+	defer func(saved config) { flags = saved }(flags)
+	flags.normalizeUnicodeFlag = true
+
+	tests := []struct {
+		in, want string
+	}{
+		{"cafe", "cafe"},
+		{"café", "cafe"},
+		{"cafe\u0301", "cafe"},
+		{"Ångström", "Angstrom"},
+		{"ｃａｆé", "cafe"},
+		{"ＡＣＭＥ－ｃｏｒｐ", "ACME-corp"},
+		{"", ""},
+	}
+	for _, tt := range tests {
+		if got := normalizeUnicode(tt.in); got != tt.want {
+			t.Errorf("normalizeUnicode(%q) = %q, want %q", tt.in, got, tt.want)
+		}
+	}
+}
+
+func TestNormalizeUnicodeDisabled(t *testing.T) {
+	defer func(saved config) { flags = saved }(flags)
+	flags.normalizeUnicodeFlag = false
+
+	if got := normalizeUnicode("café"); got != "café" {
+		t.Errorf("normalizeUnicode(%q) = %q, want it unchanged", "café", got)
+	}
+}
+
+func TestNormalizeUnicodeConcurrent(t *testing.T) {
+	defer func(saved config) { flags = saved }(flags)
+	flags.normalizeUnicodeFlag = true
+
+	var wg sync.WaitGroup
+	for i := 0; i < 8; i++ {
+		wg.Add(1)
+		go func() {
+			defer wg.Done()
+			for j := 0; j < 200; j++ {
+				if got := normalizeUnicode("ｃａｆé-ｒｅｐｏｓｉｔｏｒｙ"); got != "cafe-repository" {
+					t.Errorf("normalizeUnicode = %q, want %q", got, "cafe-repository")
+					return
+				}
+			}
+		}()
+	}
+	wg.Wait()
+}
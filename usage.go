@@ -0,0 +1,72 @@
+package main
+
+import (
+	"flag"
+	"fmt"
+)
+
+const usageEnvironment = `
+Environment variables:
+  GITHUB_ACCESS_TOKEN     GitHub token, or several separated by commas
+  GITHUB_TOKEN            GitHub token used when GITHUB_ACCESS_TOKEN is unset,
+                          as set by GitHub Actions
+  GITLAB_ACCESS_TOKEN     GitLab token
+  GITLAB_TOKEN            GitLab token used when GITLAB_ACCESS_TOKEN is unset;
+                          GitLab CI's CI_JOB_TOKEN is not supported, as job
+                          tokens cannot use the search endpoints
+  GITLAB_URL              self-hosted GitLab URL, or several separated by commas
+                          (default: https://gitlab.com)
+  GITLAB_ACCESS_TOKEN_<HOST>
+                          token for the GitLab instance at HOST, such as
+                          GITLAB_ACCESS_TOKEN_GITLAB_EXAMPLE_COM
+  BITBUCKET_USERNAME      Bitbucket Cloud username
+  BITBUCKET_APP_PASSWORD  Bitbucket Cloud app password
+  GITEA_URL               Gitea or Forgejo instance URL
+  GITEA_TOKEN             Gitea or Forgejo token
+  AZURE_DEVOPS_TOKEN      Azure DevOps personal access token (with -az-org)
+  SRHT_TOKEN              SourceHut personal access token
+  HTTPS_PROXY, HTTP_PROXY proxy for API requests, unless -proxy is given
+  NO_COLOR                disable colored output
+
+Platforms without credentials are skipped unless they are named with
+-platforms, in which case missing credentials are an error.
+`
+
+const usageExitCodes = `
+Exit codes:
+  %-4d at least one result was found
+  %-4d one or more searches failed
+  %-4d all searches succeeded but nothing was found
+  %-4d the run was interrupted
+`
+
+const usageExamples = `
+Examples:
+  # Search GitHub and GitLab for organizations, repositories, and users
+  cat wordlist.txt | %[1]s -o -r -u
+
+  # Turn a list of URLs into words and search GitHub organizations
+  cat urls.txt | %[1]s -c -o -platforms github
+
+  # Keep only exact repository matches as JSON and pull out their URLs
+  echo acme | %[1]s -r -exact -urls -json | jq -r .url
+
+  # Show the queries a wordlist would send without making requests
+  %[1]s -w wordlist.txt -permute -dry-run -o
+`
+
+// usage prints the command line, the flags, and the environment variables,
+// exit codes, and examples the flag descriptions leave out.
+func usage() {
+	w := flag.CommandLine.Output()
+	name := flag.CommandLine.Name()
+
+	fmt.Fprintf(w, "Usage: %s [flags] [word ...]\n\n", name)
+	fmt.Fprintln(w, "Searches code hosting platforms for organizations, repositories, and users")
+	fmt.Fprintln(w, "matching each word given as an argument, in -w, or on stdin.")
+	fmt.Fprintln(w, "\nFlags:")
+	flag.PrintDefaults()
+	fmt.Fprint(w, usageEnvironment)
+	fmt.Fprintf(w, usageExitCodes, exitFound, exitError, exitNoResults, exitInterrupted)
+	fmt.Fprintf(w, usageExamples, name)
+}
@@ -0,0 +1,113 @@
+package main
+
+import (
+	"bytes"
+	"context"
+	"encoding/json"
+	"fmt"
+	"net/http"
+	"net/url"
+	"strings"
+
+	"github.com/codingo/dorky/pkg/dorky"
+)
+
+// webhookResults collects every result reported during the run for
+// -webhook. Callers must hold outputMu.
+var webhookResults []dorky.Result
+
+// maxWebhookLines bounds how many results a Slack or Discord message lists.
+const maxWebhookLines = 20
+
+// sendWebhook posts the results reported during the run to -webhook, as a
+// Slack or Discord message when the URL is one of theirs and as JSON
+// otherwise. Failures are reported but do not change the exit code.
+func sendWebhook(ctx context.Context, webhookURL string, results []dorky.Result) {
+	if len(results) == 0 {
+		verbosePrint("No results to send to the webhook\n")
+		return
+	}
+
+	body, err := json.Marshal(webhookPayload(webhookURL, results))
+	if err != nil {
+		errorPrint("Error encoding webhook payload: %s\n", err)
+		return
+	}
+
+	req, err := http.NewRequestWithContext(ctx, http.MethodPost, webhookURL, bytes.NewReader(body))
+	if err != nil {
+		errorPrint("Error creating webhook request: %s\n", err)
+		return
+	}
+	req.Header.Set("Content-Type", "application/json")
+
+	resp, err := dorky.NewHTTPClient(clientOptions(flags)).Do(req)
+	if err != nil {
+		errorPrint("Error sending webhook: %s\n", err)
+		return
+	}
+	resp.Body.Close()
+	if resp.StatusCode < 200 || resp.StatusCode > 299 {
+		errorPrint("Warning: webhook returned %s\n", resp.Status)
+		return
+	}
+	verbosePrint("Sent %d results to the webhook\n", len(results))
+}
+
+// webhookPayload returns the body posted to webhookURL.
+func webhookPayload(webhookURL string, results []dorky.Result) interface{} {
+	u, err := url.Parse(webhookURL)
+	if err != nil {
+		u = &url.URL{}
+	}
+
+	switch {
+	case u.Host == "hooks.slack.com":
+		return map[string]string{"text": webhookText(results)}
+	case (u.Host == "discord.com" || u.Host == "discordapp.com") && strings.HasPrefix(u.Path, "/api/webhooks/"):
+		return map[string]string{"content": webhookText(results)}
+	}
+
+	records := make([]jsonRecord, len(results))
+	for i, r := range results {
+		// The URL is what a notification is acted on, so it is sent
+		// without -urls too.
+		records[i] = newJSONRecord(r)
+		records[i].URL = r.URL
+	}
+	return struct {
+		Count   int          `json:"count"`
+		Results []jsonRecord `json:"results"`
+	}{len(results), records}
+}
+
+// webhookText summarizes results as a chat message: a count and the first
+// maxWebhookLines results. Discord rejects messages over 2000 characters, so
+// the list is kept well short of that.
+func webhookText(results []dorky.Result) string {
+	var b strings.Builder
+	found := "results"
+	if len(results) == 1 {
+		found = "result"
+	}
+	if flags.seenFileFlag != "" {
+		found = "new " + found
+	}
+	fmt.Fprintf(&b, "dorky found %d %s:\n", len(results), found)
+
+	for i, r := range results {
+		if i == maxWebhookLines {
+			fmt.Fprintf(&b, "...and %d more\n", len(results)-i)
+			break
+		}
+		line := fmt.Sprintf("- %s %s: %s", platformNames[r.Platform], r.Category, r.Name)
+		if r.URL != "" {
+			line += " " + r.URL
+		}
+		if runes := []rune(line); len(runes) > 90 {
+			line = string(runes[:87]) + "..."
+		}
+		b.WriteString(line + "\n")
+	}
+	return b.String()
+}